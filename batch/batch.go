@@ -0,0 +1,249 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package batch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ymhhh/tax/handlers"
+)
+
+// Result 批量计算的结果
+type Result struct {
+	// 按员工在花名册中首次出现的顺序排列，不含失败的员工
+	Employees []*EmployeeResult `yaml:"employees" json:"employees"`
+	// 失败的行，按行号排列
+	Errors []RowError `yaml:"errors" json:"errors"`
+
+	Summary Summary `yaml:"summary" json:"summary"`
+}
+
+// EmployeeResult 一名员工的计算结果
+type EmployeeResult struct {
+	ID           string `yaml:"id" json:"id"`
+	Name         string `yaml:"name" json:"name"`
+	Jurisdiction string `yaml:"jurisdiction" json:"jurisdiction"`
+	IDNumber     string `yaml:"id_number,omitempty" json:"id_number,omitempty"`
+	BankAccount  string `yaml:"bank_account,omitempty" json:"bank_account,omitempty"`
+	Rows         []int  `yaml:"rows" json:"rows"`
+
+	Taxes *handlers.MonthlyTaxes `yaml:"taxes" json:"taxes"`
+	Total Totals                 `yaml:"total" json:"total"`
+}
+
+// Totals 金额合计
+type Totals = handlers.Totals
+
+// Money 金额
+type Money = handlers.Money
+
+// totalsCells 合计转为表格的单元格
+func totalsCells(p *Totals) []string {
+	return []string{p.Income.String(), p.Insurances.String(), p.AccumulationFund.String(),
+		p.Taxation.String(), p.RestSalary.String(), p.CompanyInsurances.String(),
+		p.CompanyAccumulationFund.String(), p.CompanyCost.String()}
+}
+
+// Summary 公司汇总
+type Summary struct {
+	Employees int `yaml:"employees" json:"employees"` // 计算成功的员工数
+	Failed    int `yaml:"failed" json:"failed"`       // 失败的员工数
+
+	Totals `yaml:",inline" json:",inline"`
+
+	// 按月份汇总
+	Months []*MonthSummary `yaml:"months" json:"months"`
+}
+
+// MonthSummary 某月的公司汇总
+type MonthSummary struct {
+	Month     handlers.YearMonth `yaml:"month" json:"month"`
+	Employees int                `yaml:"employees" json:"employees"`
+
+	Totals `yaml:",inline" json:",inline"`
+}
+
+// Run 使用 workers 个 goroutine 并发计算花名册，结果与花名册的顺序一致，单个员工失败不影响其他员工
+func Run(taxes *handlers.TaxesHandler, roster *Roster, workers int) *Result {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]*EmployeeResult, len(roster.Employees))
+	errs := make([]*RowError, len(roster.Employees))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results[index], errs[index] = calc(taxes, roster.Employees[index])
+			}
+		}()
+	}
+	for i, e := range roster.Employees {
+		if len(e.Errors) == 0 {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	result := &Result{}
+	result.Errors = append(result.Errors, roster.Errors...)
+	for i, e := range roster.Employees {
+		switch {
+		case len(e.Errors) > 0:
+			result.Errors = append(result.Errors, e.Errors...)
+			result.Summary.Failed++
+		case errs[i] != nil:
+			result.Errors = append(result.Errors, *errs[i])
+			result.Summary.Failed++
+		default:
+			result.Employees = append(result.Employees, results[i])
+		}
+	}
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+	result.summarize()
+	return result
+}
+
+// calc 计算一名员工，失败时返回出错月份所在行的错误
+func calc(taxes *handlers.TaxesHandler, e *Employee) (*EmployeeResult, *RowError) {
+	err := e.Salaries.Validate()
+	var monthlyTaxes *handlers.MonthlyTaxes
+	if err == nil {
+		monthlyTaxes, err = taxes.Calc(e.Salaries)
+	}
+	if err != nil {
+		return nil, &RowError{Row: e.row(err), EmployeeID: e.ID, Reason: err.Error()}
+	}
+
+	result := &EmployeeResult{
+		ID:           e.ID,
+		Name:         e.Name,
+		Jurisdiction: e.Jurisdiction,
+		IDNumber:     e.IDNumber,
+		BankAccount:  e.BankAccount,
+		Rows:         e.Rows,
+		Taxes:        monthlyTaxes,
+	}
+	result.Total = monthlyTaxes.Totals()
+	return result, nil
+}
+
+// summarize 按月份及全年汇总
+func (p *Result) summarize() {
+	months := map[handlers.YearMonth]*MonthSummary{}
+	for _, e := range p.Employees {
+		p.Summary.Employees++
+		p.Summary.Add(e.Total)
+		for _, t := range e.Taxes.Taxes {
+			month := handlers.YearMonth{Year: t.Year, Month: t.Month}
+			summary, ok := months[month]
+			if !ok {
+				summary = &MonthSummary{Month: month}
+				months[month] = summary
+				p.Summary.Months = append(p.Summary.Months, summary)
+			}
+			summary.Employees++
+			summary.Add(t.Totals())
+		}
+	}
+	sort.Slice(p.Summary.Months, func(i, j int) bool {
+		return p.Summary.Months[i].Month.Before(p.Summary.Months[j].Month)
+	})
+}
+
+const (
+	printEmployeeInfor = "%s %s, 收入: %12.2f, 社保: %10.2f, 公积金: %10.2f, 个税: %10.2f, 税后收入: %12.2f"
+	printSummaryInfor  = "%s, 人数: %d, 收入: %12.2f, 个税: %10.2f, 税后收入: %12.2f, 用工成本: %12.2f"
+)
+
+// Print 打印信息
+func (p *Result) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *Result) Fprint(w io.Writer) {
+	for _, e := range p.Employees {
+		fmt.Fprintln(w, fmt.Sprintf(printEmployeeInfor, e.ID, e.Name,
+			e.Total.Income, e.Total.Insurances, e.Total.AccumulationFund, e.Total.Taxation, e.Total.RestSalary))
+	}
+	for _, err := range p.Errors {
+		fmt.Fprintln(w, "失败: "+err.Error())
+	}
+
+	fmt.Fprintln(w, fmt.Sprintf("公司汇总, 成功: %d 人, 失败: %d 人", p.Summary.Employees, p.Summary.Failed))
+	for _, m := range p.Summary.Months {
+		fmt.Fprintln(w, fmt.Sprintf("\t"+printSummaryInfor, m.Month, m.Employees,
+			m.Income, m.Taxation, m.RestSalary, m.CompanyCost))
+	}
+	fmt.Fprintln(w, fmt.Sprintf("\t"+printSummaryInfor, "合计", p.Summary.Employees,
+		p.Summary.Income, p.Summary.Taxation, p.Summary.RestSalary, p.Summary.CompanyCost))
+}
+
+var totalsHeaders = []string{"收入", "个人社保", "个人公积金", "个税", "税后收入", "单位社保", "单位公积金", "用工成本"}
+
+// Tables 转为表格：员工逐月明细、按月汇总，以及失败的行
+func (p *Result) Tables() []*handlers.Table {
+	employees := &handlers.Table{
+		Title:   "员工明细",
+		Headers: append([]string{"员工编号", "姓名", "月份"}, totalsHeaders...),
+		Totals:  append([]string{"合计", "", ""}, totalsCells(&p.Summary.Totals)...),
+	}
+	for _, e := range p.Employees {
+		for _, t := range e.Taxes.Taxes {
+			totals := t.Totals()
+			month := handlers.YearMonth{Year: t.Year, Month: t.Month}
+			employees.AddRow(append([]string{e.ID, e.Name, month.String()}, totalsCells(&totals)...)...)
+		}
+	}
+
+	months := &handlers.Table{
+		Title:   "公司汇总",
+		Headers: append([]string{"月份", "人数"}, totalsHeaders...),
+		Totals:  append([]string{"合计", strconv.Itoa(p.Summary.Employees)}, totalsCells(&p.Summary.Totals)...),
+	}
+	for _, m := range p.Summary.Months {
+		months.AddRow(append([]string{m.Month.String(), strconv.Itoa(m.Employees)}, totalsCells(&m.Totals)...)...)
+	}
+
+	tables := []*handlers.Table{employees, months}
+	if len(p.Errors) > 0 {
+		errors := &handlers.Table{
+			Title:   "失败的行",
+			Headers: []string{"行号", "员工编号", "原因"},
+		}
+		for _, err := range p.Errors {
+			errors.AddRow(strconv.Itoa(err.Row), err.EmployeeID, err.Reason)
+		}
+		tables = append(tables, errors)
+	}
+	return tables
+}
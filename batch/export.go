@@ -0,0 +1,88 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package batch
+
+import (
+	"fmt"
+
+	"github.com/ymhhh/tax/handlers"
+)
+
+// Export 导出给银行或税务系统导入的文件，只有一个不带合计的表格，通常输出为 csv
+type Export struct {
+	Table *handlers.Table
+}
+
+// Tables 转为表格
+func (p *Export) Tables() []*handlers.Table {
+	return []*handlers.Table{p.Table}
+}
+
+// 个税申报的所得项目
+const (
+	incomeSalary = "正常工资薪金"
+	incomeBonus  = "全年一次性奖金收入"
+)
+
+// Bank 银行代发文件：每名员工每月一行实发金额
+func (p *Result) Bank() *Export {
+	t := &handlers.Table{
+		Title:   "银行代发",
+		Headers: []string{"员工编号", "姓名", "银行账号", "月份", "实发金额"},
+	}
+	for _, e := range p.Employees {
+		for _, tax := range e.Taxes.Taxes {
+			month := handlers.YearMonth{Year: tax.Year, Month: tax.Month}
+			t.AddRow(e.ID, e.Name, e.BankAccount, month.String(), tax.RestSalary.String())
+		}
+	}
+	return &Export{Table: t}
+}
+
+// Declaration 个税扣缴申报文件：按累计预扣法逐月列出工资薪金，单独计税的全年一次性奖金另起一行
+func (p *Result) Declaration() *Export {
+	t := &handlers.Table{
+		Title: "个税扣缴申报",
+		Headers: []string{"员工编号", "姓名", "证件号码", "所得期间", "所得项目", "本期收入", "本期专项扣除", "本期专项附加扣除",
+			"累计收入", "累计减除费用", "累计专项扣除", "累计专项附加扣除", "累计应纳税所得额", "税率",
+			"累计应纳税额", "累计已预缴税额", "本期应预扣预缴税额"},
+	}
+	for _, e := range p.Employees {
+		for _, tax := range e.Taxes.Taxes {
+			month := handlers.YearMonth{Year: tax.Year, Month: tax.Month}.String()
+			income := tax.Salary + tax.SubsidyAmount + tax.ExtraAmount
+			if tax.BonusMethod == handlers.BonusMerged {
+				income += tax.Bonus
+			}
+			ledger := tax.Ledger
+			t.AddRow(e.ID, e.Name, e.IDNumber, month, incomeSalary, income.String(),
+				(tax.Insurances + tax.AccumulationFund).String(),
+				(tax.DeductibleAmount + tax.SpecialAdditionalDeduction).String(),
+				ledger.Income.String(), ledger.Deduction.String(), ledger.SpecialDeduction.String(),
+				ledger.SpecialAdditionalDeduction.String(), ledger.TaxableIncome.String(),
+				fmt.Sprintf("%g", tax.TaxRate), ledger.TaxPayable.String(),
+				(ledger.WithheldTax - tax.Taxation).String(), tax.Taxation.String())
+
+			if tax.BonusMethod == handlers.BonusSeparate && tax.Bonus > 0 {
+				t.AddRow(e.ID, e.Name, e.IDNumber, month, incomeBonus, tax.Bonus.String(), "", "",
+					"", "", "", "", "", "", "", "", tax.BonusTaxation.String())
+			}
+		}
+	}
+	return &Export{Table: t}
+}
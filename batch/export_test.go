@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package batch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ymhhh/tax/catalog"
+)
+
+func TestExports(t *testing.T) {
+	taxes, err := catalog.Load()
+	if err != nil {
+		t.Fatalf("加载内置政策失败: %v", err)
+	}
+	roster, err := ParseRoster([][]string{
+		{"员工编号", "姓名", "地区", "月份", "月薪", "公积金比例", "年终奖", "证件号码", "银行账号"},
+		{"E001", "张三", "beijing", "2024-01", "30000", "12", "", "110101199001011234", "6222000011112222"},
+		{"E001", "张三", "beijing", "2024-02", "30000", "12", "60000", "", ""},
+	})
+	if err != nil {
+		t.Fatalf("解析花名册失败: %v", err)
+	}
+	result := Run(taxes, roster, 1)
+
+	bank := result.Bank().Table
+	if want := [][]string{
+		{"E001", "张三", "6222000011112222", "2024-01", "22699.59"},
+		{"E001", "张三", "6222000011112222", "2024-02", "76875.01"},
+	}; !reflect.DeepEqual(bank.Rows, want) {
+		t.Errorf("银行代发 %v, 应为 %v", bank.Rows, want)
+	}
+
+	declaration := result.Declaration().Table
+	if len(declaration.Rows) != 3 {
+		t.Fatalf("个税申报应为 3 行, 实际 %d 行: %v", len(declaration.Rows), declaration.Rows)
+	}
+	// 2月: 累计已预缴为1月的税额，本期应预扣为累计应纳税额减去累计已预缴
+	if got, want := declaration.Rows[1][13:], []string{"10", "1129.40", "547.41", "581.99"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("2月个税 %v, 应为 %v", got, want)
+	}
+	if got := declaration.Rows[2]; got[4] != incomeBonus || got[5] != "60000.00" || got[16] != "5790.00" {
+		t.Errorf("年终奖行 %v", got)
+	}
+	for _, row := range declaration.Rows {
+		if len(row) != len(declaration.Headers) {
+			t.Errorf("列数 %d 与表头 %d 不一致: %v", len(row), len(declaration.Headers), row)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package batch 按员工花名册批量计算工资个税
+package batch
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ymhhh/tax/charset"
+	"github.com/ymhhh/tax/handlers"
+	"github.com/ymhhh/tax/xlsx"
+)
+
+// Roster 员工花名册，每行为一名员工一个月的工资
+type Roster struct {
+	// 按在花名册中首次出现的顺序排列
+	Employees []*Employee
+	// 无法归属到员工的行，如未填写员工编号
+	Errors []RowError
+}
+
+// Employee 一名员工全年的工资
+type Employee struct {
+	ID           string
+	Name         string
+	Jurisdiction string
+	// 证件号码、银行账号，用于个税申报和银行代发的导出
+	IDNumber    string
+	BankAccount string
+	// 所在的行号，按月份排列
+	Rows     []int
+	Salaries *handlers.Salaries
+	// 解析失败的行，有失败的行时不计算该员工
+	Errors []RowError
+
+	// 各月份所在的行号，以及奖金所在的行号，用于定位计算失败的行
+	monthRows map[handlers.YearMonth]int
+	bonusRow  int
+}
+
+// row 错误所在的行号：某月的错误为该月所在的行，奖金的错误为奖金所在的行，其余为第一行
+func (p *Employee) row(err error) int {
+	var monthErr *handlers.MonthError
+	if errors.As(err, &monthErr) {
+		if row, ok := p.monthRows[monthErr.Month]; ok {
+			return row
+		}
+	}
+	if p.Salaries != nil && p.Salaries.Bonus != nil && p.Salaries.Bonus.Validate() != nil {
+		return p.bonusRow
+	}
+	if len(p.Rows) > 0 {
+		return p.Rows[0]
+	}
+	return 0
+}
+
+// RowError 处理失败的行
+type RowError struct {
+	Row        int    `yaml:"row" json:"row"`
+	EmployeeID string `yaml:"employee_id" json:"employee_id"`
+	Reason     string `yaml:"reason" json:"reason"`
+}
+
+func (p RowError) Error() string {
+	return fmt.Sprintf("第 %d 行(%s): %s", p.Row, p.EmployeeID, p.Reason)
+}
+
+// row 花名册中的一行
+type row struct {
+	index int
+
+	id          string
+	name        string
+	city        string
+	idNumber    string
+	bankAccount string
+
+	salary      handlers.MonthlySalary
+	bonus       handlers.Money
+	bonusMethod handlers.BonusMethod
+}
+
+// column 花名册的列，表头可以是 key 或 names 中的任意一个
+type column struct {
+	key      string
+	names    []string
+	required bool
+	set      func(r *row, value string) error
+}
+
+func moneyColumn(key string, names []string, field func(r *row) *handlers.Money) column {
+	return column{key: key, names: names, set: func(r *row, value string) error {
+		amount, err := handlers.ParseMoney(strings.Replace(value, ",", "", -1))
+		if err != nil {
+			return err
+		}
+		*field(r) = amount
+		return nil
+	}}
+}
+
+func rateColumn(key string, names []string, field func(r *row) *float64) column {
+	return column{key: key, names: names, set: func(r *row, value string) error {
+		rate, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return fmt.Errorf("比例格式错误: %s", value)
+		}
+		*field(r) = rate
+		return nil
+	}}
+}
+
+// columns 花名册支持的列，未列出的列忽略
+var columns = []column{
+	{key: "employee_id", names: []string{"员工编号", "工号"}, required: true,
+		set: func(r *row, value string) error { r.id = value; return nil }},
+	{key: "name", names: []string{"姓名"},
+		set: func(r *row, value string) error { r.name = value; return nil }},
+	{key: "city", names: []string{"jurisdiction", "地区", "城市"},
+		set: func(r *row, value string) error { r.city = value; return nil }},
+	{key: "id_number", names: []string{"证件号码", "身份证号"},
+		set: func(r *row, value string) error { r.idNumber = value; return nil }},
+	{key: "bank_account", names: []string{"银行账号", "银行卡号"},
+		set: func(r *row, value string) error { r.bankAccount = value; return nil }},
+	{key: "month", names: []string{"月份", "所属月份"}, required: true,
+		set: func(r *row, value string) (err error) { r.salary.Month, err = parseMonth(value); return }},
+	{key: "employer", names: []string{"任职单位"},
+		set: func(r *row, value string) error { r.salary.Employer = value; return nil }},
+	{key: "residence", names: []string{"户口类型"}, set: func(r *row, value string) error {
+		residence, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("户口类型需为 0 或 1: %s", value)
+		}
+		t := handlers.ResidenceType(residence)
+		r.salary.Residence = &t
+		return nil
+	}},
+	{key: "endowment", names: []string{"养老类型"}, set: func(r *row, value string) error {
+		endowment, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("养老类型需为 0 或 1: %s", value)
+		}
+		t := handlers.EndowmentType(endowment)
+		r.salary.Endowment = &t
+		return nil
+	}},
+	moneyColumn("threshold", []string{"起征线"}, func(r *row) *handlers.Money { return &r.salary.Threshold }),
+	moneyColumn("salary", []string{"月薪"}, func(r *row) *handlers.Money { return &r.salary.Salary }),
+	moneyColumn("subsidy_amount", []string{"补贴", "补助"}, func(r *row) *handlers.Money { return &r.salary.SubsidyAmount }),
+	moneyColumn("deductible_amount", []string{"抵扣金额", "专项附加扣除"},
+		func(r *row) *handlers.Money { return &r.salary.DeductibleAmount }),
+	moneyColumn("extra_amount", []string{"额外工资"}, func(r *row) *handlers.Money { return &r.salary.ExtraAmount }),
+	rateColumn("accumulation_fund_rate", []string{"公积金比例"},
+		func(r *row) *float64 { return &r.salary.AccumulationFundRate }),
+	moneyColumn("accumulation_fund_base", []string{"公积金基数"},
+		func(r *row) *handlers.Money { return &r.salary.AccumulationFundBase }),
+	{key: "base_source", names: []string{"基数来源"},
+		set: func(r *row, value string) error { r.salary.BaseSource = handlers.BaseSource(value); return nil }},
+	moneyColumn("average_wage", []string{"上年度月平均工资"}, func(r *row) *handlers.Money { return &r.salary.AverageWage }),
+	moneyColumn("endowment_base", []string{"养老基数"}, func(r *row) *handlers.Money { return &r.salary.EndowmentBase }),
+	moneyColumn("medical_base", []string{"医疗基数"}, func(r *row) *handlers.Money { return &r.salary.MedicalBase }),
+	moneyColumn("unemployment_base", []string{"失业基数"},
+		func(r *row) *handlers.Money { return &r.salary.UnemploymentBase }),
+	moneyColumn("employment_injury_base", []string{"工伤基数"},
+		func(r *row) *handlers.Money { return &r.salary.EmploymentInjuryBase }),
+	moneyColumn("birth_base", []string{"生育基数"}, func(r *row) *handlers.Money { return &r.salary.BirthBase }),
+	moneyColumn("serious_medical_base", []string{"大病基数"},
+		func(r *row) *handlers.Money { return &r.salary.SeriousMedicalBase }),
+	moneyColumn("bonus", []string{"奖金", "年终奖", "全年一次性奖金"}, func(r *row) *handlers.Money { return &r.bonus }),
+	{key: "bonus_method", names: []string{"奖金计税方式"},
+		set: func(r *row, value string) error { r.bonusMethod = handlers.BonusMethod(value); return nil }},
+}
+
+// 未填写起征线时使用的金额
+var defaultThreshold = handlers.NewMoney(5000)
+
+// ReadRoster 读取花名册，按扩展名区分 xlsx 和 csv
+func ReadRoster(file string) (*Roster, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records [][]string
+	if strings.EqualFold(filepath.Ext(file), ".xlsx") {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		records, err = xlsx.ReadRows(f, info.Size())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		r, _, err := charset.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("读取 csv 失败: %v", err)
+		}
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		if records, err = reader.ReadAll(); err != nil {
+			return nil, fmt.Errorf("读取 csv 失败: %v", err)
+		}
+	}
+	return ParseRoster(records)
+}
+
+// ParseRoster 解析花名册，第一行为表头；单行的错误记录在员工或 Roster.Errors 中，不影响其他员工
+func ParseRoster(records [][]string) (*Roster, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("花名册为空")
+	}
+
+	header, err := parseHeader(records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	roster := &Roster{}
+	employees := map[string]*Employee{}
+	rows := map[*Employee][]*row{}
+	for i, record := range records[1:] {
+		if isBlank(record) {
+			continue
+		}
+
+		r := &row{index: i + 2}
+		r.salary.Threshold = defaultThreshold
+		err := r.parse(header, record)
+
+		if r.id == "" {
+			roster.Errors = append(roster.Errors, RowError{Row: r.index, Reason: "未填写员工编号"})
+			continue
+		}
+		e, ok := employees[r.id]
+		if !ok {
+			e = &Employee{ID: r.id, Name: r.name, Jurisdiction: r.city}
+			employees[r.id] = e
+			roster.Employees = append(roster.Employees, e)
+		}
+		if err == nil && r.city != e.Jurisdiction {
+			err = fmt.Errorf("同一员工的地区需相同: %s 与 %s", r.city, e.Jurisdiction)
+		}
+		if err != nil {
+			e.Errors = append(e.Errors, RowError{Row: r.index, EmployeeID: r.id, Reason: err.Error()})
+			continue
+		}
+		if e.IDNumber == "" {
+			e.IDNumber = r.idNumber
+		}
+		if e.BankAccount == "" {
+			e.BankAccount = r.bankAccount
+		}
+		rows[e] = append(rows[e], r)
+	}
+
+	for _, e := range roster.Employees {
+		e.build(rows[e])
+	}
+	return roster, nil
+}
+
+// parseHeader 表头中各列对应的 column，未知的列为 nil
+func parseHeader(header []string) ([]*column, error) {
+	found := map[string]bool{}
+	result := make([]*column, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for j := range columns {
+			c := &columns[j]
+			if name != c.key && !containsName(c.names, name) {
+				continue
+			}
+			if found[c.key] {
+				return nil, fmt.Errorf("表头重复: %s", header[i])
+			}
+			found[c.key] = true
+			result[i] = c
+		}
+	}
+	for _, c := range columns {
+		if c.required && !found[c.key] {
+			return nil, fmt.Errorf("表头缺少 %s(%s)", c.key, strings.Join(c.names, "/"))
+		}
+	}
+	return result, nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parse 解析一行，出错时仍解析员工编号，以便归属到员工
+func (p *row) parse(header []*column, record []string) error {
+	var firstErr error
+	for i, value := range record {
+		value = strings.TrimSpace(value)
+		if i >= len(header) || header[i] == nil || value == "" {
+			continue
+		}
+		if err := header[i].set(p, value); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", header[i].key, err)
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if p.salary.Month.IsZero() {
+		return fmt.Errorf("未填写月份")
+	}
+	return nil
+}
+
+// build 按月份排列各行，生成薪资配置，奖金所在行为发放月份
+func (p *Employee) build(rows []*row) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].salary.Month.Before(rows[j].salary.Month)
+	})
+
+	ss := &handlers.Salaries{}
+	ss.Jurisdiction = p.Jurisdiction
+	p.monthRows = map[handlers.YearMonth]int{}
+	for _, r := range rows {
+		if r.bonus > 0 {
+			if ss.Bonus != nil {
+				p.Errors = append(p.Errors, RowError{Row: r.index, EmployeeID: p.ID, Reason: "全年一次性奖金只能发放一次"})
+				continue
+			}
+			ss.Bonus = &handlers.Bonus{Amount: r.bonus, Month: r.salary.Month.Month, Method: r.bonusMethod}
+			p.bonusRow = r.index
+		}
+		if len(ss.MonthlySalaries) == 0 {
+			if r.salary.Residence != nil {
+				ss.Residence = *r.salary.Residence
+			}
+			if r.salary.Endowment != nil {
+				ss.Endowment = *r.salary.Endowment
+			}
+		}
+		ss.MonthlySalaries = append(ss.MonthlySalaries, r.salary)
+		p.Rows = append(p.Rows, r.index)
+		p.monthRows[r.salary.Month] = r.index
+	}
+	p.Salaries = ss
+}
+
+// excel 日期序列号的起点
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// parseMonth 解析月份，支持 2024-01、2024/1、2024.03、2024-01-15，以及 Excel 的日期序列号
+// 先按年月格式解析，不是合理的年月时才作为序列号，避免 2024.03 被当作序列号
+func parseMonth(value string) (handlers.YearMonth, error) {
+	if month, ok := parseYearMonth(value); ok {
+		return month, nil
+	}
+	if !strings.ContainsAny(value, "-/") {
+		if serial, err := strconv.ParseFloat(value, 64); err == nil && serial >= 1 {
+			t := excelEpoch.AddDate(0, 0, int(math.Floor(serial)))
+			return handlers.YearMonth{Year: t.Year(), Month: int(t.Month())}, nil
+		}
+	}
+	return handlers.YearMonth{}, fmt.Errorf("月份格式错误: %s", value)
+}
+
+// parseYearMonth 按 年-月、年/月、年.月 解析，可以带日
+func parseYearMonth(value string) (handlers.YearMonth, bool) {
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '-' || r == '/' || r == '.'
+	})
+	if len(parts) != 2 && len(parts) != 3 {
+		return handlers.YearMonth{}, false
+	}
+	year, err := strconv.Atoi(parts[0])
+	if err != nil || year < 1900 || year > 9999 {
+		return handlers.YearMonth{}, false
+	}
+	month, err := strconv.Atoi(parts[1])
+	if err != nil || month < 1 || month > 12 {
+		return handlers.YearMonth{}, false
+	}
+	return handlers.YearMonth{Year: year, Month: month}, true
+}
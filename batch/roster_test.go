@@ -0,0 +1,96 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package batch
+
+import (
+	"testing"
+
+	"github.com/ymhhh/tax/catalog"
+	"github.com/ymhhh/tax/handlers"
+)
+
+func TestParseMonth(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want handlers.YearMonth
+	}{
+		{"2024-03", handlers.YearMonth{Year: 2024, Month: 3}},
+		{"2024/3", handlers.YearMonth{Year: 2024, Month: 3}},
+		{"2024.03", handlers.YearMonth{Year: 2024, Month: 3}},
+		{"2024.12", handlers.YearMonth{Year: 2024, Month: 12}},
+		{"2024-12-05", handlers.YearMonth{Year: 2024, Month: 12}},
+		// Excel 日期序列号，45306 为 2024-01-15
+		{"45306", handlers.YearMonth{Year: 2024, Month: 1}},
+		{"45306.5", handlers.YearMonth{Year: 2024, Month: 1}},
+	} {
+		got, err := parseMonth(c.in)
+		if err != nil {
+			t.Errorf("parseMonth(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseMonth(%q) = %s, 应为 %s", c.in, got, c.want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "0", "2024-13", "2024/0"} {
+		if got, err := parseMonth(in); err == nil {
+			t.Errorf("parseMonth(%q) = %s, 应返回错误", in, got)
+		}
+	}
+}
+
+func TestRowErrorOnLaterRow(t *testing.T) {
+	taxes, err := catalog.Load()
+	if err != nil {
+		t.Fatalf("加载内置政策失败: %v", err)
+	}
+
+	header := []string{"员工编号", "姓名", "地区", "月份", "月薪", "公积金比例", "奖金", "奖金计税方式"}
+	for _, c := range []struct {
+		name string
+		bad  []string
+	}{
+		{"校验失败", []string{"E001", "张三", "beijing", "2024-03", "20000", "150", "", ""}},
+		{"不在计算年度", []string{"E001", "张三", "beijing", "2025-03", "20000", "12", "", ""}},
+		{"奖金", []string{"E001", "张三", "beijing", "2024-03", "20000", "12", "30000", "unknown"}},
+	} {
+		records := [][]string{
+			header,
+			{"E001", "张三", "beijing", "2024-01", "20000", "12", "", ""},
+			{"E001", "张三", "beijing", "2024-02", "20000", "12", "", ""},
+			c.bad,
+			{"E002", "李四", "beijing", "2024-01", "15000", "12", "", ""},
+		}
+		roster, err := ParseRoster(records)
+		if err != nil {
+			t.Fatalf("%s: 解析花名册失败: %v", c.name, err)
+		}
+
+		result := Run(taxes, roster, 2)
+		if len(result.Errors) != 1 {
+			t.Fatalf("%s: 失败的行 %v, 应为 1 行", c.name, result.Errors)
+		}
+		if got := result.Errors[0]; got.Row != 4 || got.EmployeeID != "E001" {
+			t.Errorf("%s: 失败的行为 %s, 应为第 4 行(E001)", c.name, got.Error())
+		}
+		if len(result.Employees) != 1 || result.Employees[0].ID != "E002" {
+			t.Errorf("%s: 其他员工应计算成功: %v", c.name, result.Employees)
+		}
+	}
+}
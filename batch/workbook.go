@@ -0,0 +1,159 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package batch
+
+import (
+	"github.com/ymhhh/tax/handlers"
+	"github.com/ymhhh/tax/xlsx"
+)
+
+// Workbook 转为工作簿：月度汇总、每名员工一个工作表、用工成本，以及失败的行
+func (p *Result) Workbook() *xlsx.Workbook {
+	wb := &xlsx.Workbook{}
+
+	summary := wb.AddSheet("月度汇总")
+	summary.FreezeRows = 1
+	summary.AddRow(xlsx.Header(append([]string{"月份", "人数"}, totalsHeaders...)...)...)
+	for _, m := range p.Summary.Months {
+		summary.AddRow(append([]xlsx.Cell{xlsx.Text(m.Month.String()), xlsx.Number(float64(m.Employees))},
+			totalsNumbers(&m.Totals)...)...)
+	}
+	summary.AddRow(xlsx.Bold(append([]xlsx.Cell{xlsx.Text("合计"), xlsx.Number(float64(p.Summary.Employees))},
+		totalsNumbers(&p.Summary.Totals)...))...)
+
+	for _, e := range p.Employees {
+		e.addSheet(wb)
+	}
+
+	cost := wb.AddSheet("用工成本")
+	cost.FreezeRows = 1
+	cost.AddRow(xlsx.Header("员工编号", "姓名", "地区", "收入", "单位社保", "单位公积金", "用工成本")...)
+	for _, e := range p.Employees {
+		cost.AddRow(xlsx.Text(e.ID), xlsx.Text(e.Name), xlsx.Text(e.Jurisdiction), money(e.Total.Income),
+			money(e.Total.CompanyInsurances), money(e.Total.CompanyAccumulationFund), money(e.Total.CompanyCost))
+	}
+	cost.AddRow(xlsx.Bold([]xlsx.Cell{xlsx.Text("合计"), {}, {}, money(p.Summary.Income),
+		money(p.Summary.CompanyInsurances), money(p.Summary.CompanyAccumulationFund), money(p.Summary.CompanyCost)})...)
+
+	if len(p.Errors) > 0 {
+		errors := wb.AddSheet("失败的行")
+		errors.FreezeRows = 1
+		errors.AddRow(xlsx.Header("行号", "员工编号", "原因")...)
+		for _, err := range p.Errors {
+			errors.AddRow(xlsx.Number(float64(err.Row)), xlsx.Text(err.EmployeeID), xlsx.Text(err.Reason))
+		}
+	}
+	return wb
+}
+
+// addSheet 员工的逐月明细，社保按险种分为个人、单位两组列
+func (p *EmployeeResult) addSheet(wb *xlsx.Workbook) {
+	var items, names []string
+	for _, t := range p.Taxes.Taxes {
+		if t.InsurancesResult == nil {
+			continue
+		}
+		for _, item := range t.InsurancesResult.Items {
+			if !contains(items, item.Item.Key) {
+				items = append(items, item.Item.Key)
+				names = append(names, item.Item.Name)
+			}
+		}
+	}
+
+	headers := []string{"月份", "任职单位", "公积金基数", "公积金比例", "月薪", "补贴", "额外工资", "奖金"}
+	for _, name := range names {
+		headers = append(headers, name+"(个人)")
+	}
+	headers = append(headers, "个人社保", "个人公积金", "专项附加扣除", "个税", "奖金个税", "税后收入")
+	for _, name := range names {
+		headers = append(headers, name+"(单位)")
+	}
+	headers = append(headers, "单位社保", "单位公积金", "用工成本")
+
+	name := p.ID
+	if p.Name != "" {
+		name += " " + p.Name
+	}
+	sheet := wb.AddSheet(name)
+	sheet.FreezeRows = 1
+	sheet.AddRow(xlsx.Header(headers...)...)
+
+	// 金额列从奖金前的月薪开始，合计行只合计金额列
+	const amountColumn = 4
+	totals := make([]handlers.Money, len(headers)-amountColumn)
+	for _, t := range p.Taxes.Taxes {
+		month := t.Totals()
+
+		private := make([]handlers.Money, len(items))
+		company := make([]handlers.Money, len(items))
+		var fundBase handlers.Money
+		if t.InsurancesResult != nil {
+			for i, key := range items {
+				if item, ok := t.InsurancesResult.Item(key); ok {
+					private[i], company[i] = item.Private, item.Company
+				}
+			}
+		}
+		if t.AccumulationFundResult != nil {
+			fundBase = t.AccumulationFundResult.Base
+		}
+
+		amounts := []handlers.Money{t.Salary, t.SubsidyAmount, t.ExtraAmount, t.Bonus}
+		amounts = append(amounts, private...)
+		amounts = append(amounts, t.Insurances, t.AccumulationFund, t.SpecialAdditionalDeduction,
+			t.Taxation, t.BonusTaxation, t.RestSalary)
+		amounts = append(amounts, company...)
+		amounts = append(amounts, month.CompanyInsurances, month.CompanyAccumulationFund, month.CompanyCost)
+
+		row := []xlsx.Cell{
+			xlsx.Text(handlers.YearMonth{Year: t.Year, Month: t.Month}.String()), xlsx.Text(t.Employer),
+			money(fundBase), xlsx.Number(t.AccumulationFundRate),
+		}
+		for i, amount := range amounts {
+			totals[i] += amount
+			row = append(row, money(amount))
+		}
+		sheet.AddRow(row...)
+	}
+
+	row := []xlsx.Cell{xlsx.Text("合计"), {}, {}, {}}
+	for _, amount := range totals {
+		row = append(row, money(amount))
+	}
+	sheet.AddRow(xlsx.Bold(row)...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func money(m handlers.Money) xlsx.Cell {
+	return xlsx.Number(m.Yuan())
+}
+
+// totalsNumbers 合计转为数字单元格
+func totalsNumbers(p *Totals) []xlsx.Cell {
+	return []xlsx.Cell{money(p.Income), money(p.Insurances), money(p.AccumulationFund), money(p.Taxation),
+		money(p.RestSalary), money(p.CompanyInsurances), money(p.CompanyAccumulationFund), money(p.CompanyCost)}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package catalog
+
+// beijing 北京五险一金政策
+const beijing = `
+policies:
+  - jurisdiction: beijing
+    name: 北京
+    effective_from: 2019-01
+    effective_to: 2019-06
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 3387, max_base: 25401, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 3387, max_base: 25401, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 5080, max_base: 25401, company_rate: 10, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 3387, max_base: 25401, company_rate: 0.8, private_rate: 0.2, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 3387, max_base: 25401, company_rate: 0.8, private_rate: 0, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 4713, max_base: 25401, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 5080, max_base: 25401, company_rate: 0.8, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 3}
+    accumulation_fund: {min_base: 2120, max_base: 25401, min_rate: 5, max_rate: 12}
+  - jurisdiction: beijing
+    name: 北京
+    effective_from: 2019-07
+    effective_to: 2021-07
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 3613, max_base: 23565, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 4713, max_base: 23565, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 5557, max_base: 27786, company_rate: 10, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 3613, max_base: 23565, company_rate: 0.8, private_rate: 0.2, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 3613, max_base: 23565, company_rate: 0.8, private_rate: 0, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 4624, max_base: 23118, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 5557, max_base: 27786, company_rate: 0.8, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 3}
+    accumulation_fund: {min_base: 2200, max_base: 27786, min_rate: 5, max_rate: 12}
+  - jurisdiction: beijing
+    name: 北京
+    effective_from: 2021-08
+    effective_to: 2022-06
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 5360, max_base: 29732, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 5360, max_base: 29732, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 5360, max_base: 29732, company_rate: 9.8, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 5360, max_base: 29732, company_rate: 0.5, private_rate: 0.5, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 5360, max_base: 29732, company_rate: 0.5, private_rate: 0.5, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 5360, max_base: 29732, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 5360, max_base: 29732, company_rate: 0.8, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 3}
+    accumulation_fund: {min_base: 2320, max_base: 29732, min_rate: 5, max_rate: 12}
+  - jurisdiction: beijing
+    name: 北京
+    effective_from: 2022-07
+    effective_to: 2023-06
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 5869, max_base: 31884, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 5869, max_base: 31884, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 5869, max_base: 31884, company_rate: 9.8, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 5869, max_base: 31884, company_rate: 0.5, private_rate: 0.5, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 5869, max_base: 31884, company_rate: 0.5, private_rate: 0.5, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 5869, max_base: 31884, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 5869, max_base: 31884, company_rate: 0.8, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 3}
+    accumulation_fund: {min_base: 2320, max_base: 31884, min_rate: 5, max_rate: 12}
+  - jurisdiction: beijing
+    name: 北京
+    effective_from: 2023-07
+    effective_to: 2024-06
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 6326, max_base: 33891, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 6326, max_base: 33891, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 6326, max_base: 33891, company_rate: 9, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 6326, max_base: 33891, company_rate: 0.5, private_rate: 0.5, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 6326, max_base: 33891, company_rate: 0.5, private_rate: 0.5, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 6326, max_base: 33891, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 6326, max_base: 33891, company_rate: 0.8, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 3}
+    accumulation_fund: {min_base: 2420, max_base: 33891, min_rate: 5, max_rate: 12}
+  - jurisdiction: beijing
+    name: 北京
+    effective_from: 2024-07
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 6821, max_base: 35283, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 6821, max_base: 35283, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 6821, max_base: 35283, company_rate: 9, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 6821, max_base: 35283, company_rate: 0.5, private_rate: 0.5, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 6821, max_base: 35283, company_rate: 0.5, private_rate: 0.5, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 6821, max_base: 35283, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 6821, max_base: 35283, company_rate: 0.8, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 3}
+    accumulation_fund: {min_base: 2540, max_base: 35283, min_rate: 5, max_rate: 12}
+`
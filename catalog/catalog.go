@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package catalog 内置的各地区五险一金政策以及全国统一的个税政策
+//
+// 各地区的基数、比例为整理自公开资料的参考值，工伤保险按行业最低档登记，
+// 最新一期政策在新基数公布前继续沿用，尚未整理的早期期间沿用最早一期的参考值，
+// 使用前请以当地社保、公积金管理部门公布的数据为准
+package catalog
+
+import (
+	"sync"
+
+	"github.com/go-trellis/config"
+	"github.com/ymhhh/tax/handlers"
+)
+
+// FirstYear 内置政策覆盖的第一个年度，即累计预扣法开始施行的年度，此后每个地区每个月都有适用的政策
+const FirstYear = 2019
+
+// sources 内置的政策，个税政策排在最前
+var sources = []string{
+	national,
+	beijing,
+	shanghai,
+	shenzhen,
+	guangzhou,
+	hangzhou,
+	chengdu,
+}
+
+var (
+	once     sync.Once
+	registry *handlers.PolicyRegistry
+	loadErr  error
+)
+
+// Registry 内置的政策登记表
+func Registry() (*handlers.PolicyRegistry, error) {
+	once.Do(func() {
+		r := &handlers.PolicyRegistry{DefaultJurisdiction: "beijing"}
+		for _, source := range sources {
+			part := &handlers.PolicyRegistry{}
+			if loadErr = config.ParseYAMLConfig([]byte(source), part); loadErr != nil {
+				return
+			}
+			r.Merge(part)
+		}
+		registry = r
+	})
+	return registry, loadErr
+}
+
+// Load 生成使用内置政策的 TaxesHandler
+func Load() (*handlers.TaxesHandler, error) {
+	r, err := Registry()
+	if err != nil {
+		return nil, err
+	}
+	return &handlers.TaxesHandler{PolicyRegistry: *r}, nil
+}
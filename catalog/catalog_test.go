@@ -0,0 +1,84 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package catalog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ymhhh/tax/handlers"
+)
+
+func TestEveryCityEveryYear(t *testing.T) {
+	taxes, err := Load()
+	if err != nil {
+		t.Fatalf("加载内置政策失败: %v", err)
+	}
+	jurisdictions := taxes.Jurisdictions()
+	if len(jurisdictions) != 6 {
+		t.Fatalf("地区数量 %d, 期望 6: %v", len(jurisdictions), jurisdictions)
+	}
+	for _, jurisdiction := range jurisdictions {
+		for year := FirstYear; year <= time.Now().Year()+1; year++ {
+			for month := 1; month <= 12; month++ {
+				ym := handlers.YearMonth{Year: year, Month: month}
+				if _, err := taxes.Handler(jurisdiction, ym); err != nil {
+					t.Errorf("%s %s: %v", jurisdiction, ym, err)
+				}
+			}
+		}
+	}
+}
+
+func TestPeriodsDoNotOverlap(t *testing.T) {
+	r, err := Registry()
+	if err != nil {
+		t.Fatalf("加载内置政策失败: %v", err)
+	}
+	for _, jurisdiction := range r.Jurisdictions() {
+		policies := r.PoliciesOf(jurisdiction)
+		for i := 1; i < len(policies); i++ {
+			prev, cur := policies[i-1], policies[i]
+			if prev.EffectiveTo.AddMonths(1) != cur.EffectiveFrom {
+				t.Errorf("%s: %s 与 %s 不连续", jurisdiction, prev.Period, cur.Period)
+			}
+		}
+	}
+}
+
+func TestPolicyNotFound(t *testing.T) {
+	taxes, err := Load()
+	if err != nil {
+		t.Fatalf("加载内置政策失败: %v", err)
+	}
+	cases := []struct {
+		jurisdiction string
+		month        handlers.YearMonth
+	}{
+		{"atlantis", handlers.YearMonth{}},
+		{"atlantis", handlers.YearMonth{Year: 2024, Month: 3}},
+		{"beijing", handlers.YearMonth{Year: FirstYear - 1, Month: 12}},
+	}
+	for _, c := range cases {
+		_, err := taxes.Handler(c.jurisdiction, c.month)
+		if !errors.Is(err, handlers.ErrPolicyNotFound) {
+			t.Errorf("%s %s: 期望 ErrPolicyNotFound, 实际 %v", c.jurisdiction, c.month, err)
+		}
+	}
+}
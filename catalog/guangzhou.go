@@ -0,0 +1,65 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package catalog
+
+// guangzhou 广州五险一金政策
+const guangzhou = `
+policies:
+  # 2023-07 之前的基数尚未整理，沿用 2023 年度的参考值
+  - jurisdiction: guangzhou
+    name: 广州
+    effective_from: 2019-01
+    effective_to: 2023-06
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 4588, max_base: 26421, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 4588, max_base: 26421, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 6483, max_base: 32415, company_rate: 5.5, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 2300, max_base: 38082, company_rate: 0.32, private_rate: 0.2, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 2300, max_base: 38082, company_rate: 0.32, private_rate: 0.2, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 2300, max_base: 38082, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 6483, max_base: 32415, company_rate: 0.85, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 0}
+    accumulation_fund: {min_base: 2300, max_base: 38082, min_rate: 5, max_rate: 12}
+  - jurisdiction: guangzhou
+    name: 广州
+    effective_from: 2023-07
+    effective_to: 2024-06
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 4588, max_base: 26421, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 4588, max_base: 26421, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 6483, max_base: 32415, company_rate: 5.5, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 2300, max_base: 38082, company_rate: 0.32, private_rate: 0.2, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 2300, max_base: 38082, company_rate: 0.32, private_rate: 0.2, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 2300, max_base: 38082, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 6483, max_base: 32415, company_rate: 0.85, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 0}
+    accumulation_fund: {min_base: 2300, max_base: 38082, min_rate: 5, max_rate: 12}
+  - jurisdiction: guangzhou
+    name: 广州
+    effective_from: 2024-07
+    insurances:
+      - {key: workers_endowment, name: 职工基本养老, base_key: endowment, endowments: [0], min_base: 4588, max_base: 27501, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: office_endowment, name: 机关基本养老, base_key: endowment, endowments: [1], min_base: 4588, max_base: 27501, company_rate: 16, private_rate: 8, extra_payment: 0}
+      - {key: medical, name: 基本医疗, min_base: 6727, max_base: 33636, company_rate: 5.5, private_rate: 2, extra_payment: 0}
+      - {key: non_agricultural_unemployment, name: 非农失业, base_key: unemployment, residences: [0], min_base: 2300, max_base: 39165, company_rate: 0.32, private_rate: 0.2, extra_payment: 0}
+      - {key: agricultural_unemployment, name: 农业失业, base_key: unemployment, residences: [1], min_base: 2300, max_base: 39165, company_rate: 0.32, private_rate: 0.2, extra_payment: 0}
+      - {key: employment_injury, name: 工伤保险, min_base: 2300, max_base: 39165, company_rate: 0.2, private_rate: 0, extra_payment: 0}
+      - {key: birth, name: 生育保险, min_base: 6727, max_base: 33636, company_rate: 0.85, private_rate: 0, extra_payment: 0}
+      - {key: serious_medical, name: 大病医疗, min_base: 0, max_base: 0, company_rate: 0, private_rate: 0, extra_payment: 0}
+    accumulation_fund: {min_base: 2300, max_base: 39165, min_rate: 5, max_rate: 12}
+`
@@ -0,0 +1,136 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package catalog
+
+// national 全国统一的个税政策
+const national = `
+tax_policies:
+  - effective_from: 2019-01
+    year_tax_rates:
+      - salary_min: 0
+        salary_max: 36000
+        rate: 3
+        deducted_amount: 0
+      - salary_min: 36000
+        salary_max: 144000
+        rate: 10
+        deducted_amount: 2520
+      - salary_min: 144000
+        salary_max: 300000
+        rate: 20
+        deducted_amount: 16920
+      - salary_min: 300000
+        salary_max: 420000
+        rate: 25
+        deducted_amount: 31920
+      - salary_min: 420000
+        salary_max: 660000
+        rate: 30
+        deducted_amount: 52920
+      - salary_min: 660000
+        salary_max: 960000
+        rate: 35
+        deducted_amount: 85920
+      - salary_min: 960000
+        salary_max: 0
+        rate: 45
+        deducted_amount: 181920
+
+    reconcile:
+      basic_deduction: 60000
+      labor_expense_rate: 20
+      author_expense_rate: 20
+      author_discount_rate: 70
+      royalty_expense_rate: 20
+      exempt_tax_due: 400
+      exempt_income: 120000
+
+    special_deduction_standards:
+      - year: 2019
+        children_education: 1000
+        infant_care: 0
+        continuing_education_degree: 400
+        continuing_education_certificate: 3600
+        housing_loan_interest: 1000
+        housing_rent:
+          tier1: 1500
+          tier2: 1100
+          tier3: 800
+        elderly_support: 2000
+        elderly_support_share_max: 1000
+        serious_illness_threshold: 15000
+        serious_illness_max: 80000
+      - year: 2022
+        children_education: 1000
+        infant_care: 1000
+        continuing_education_degree: 400
+        continuing_education_certificate: 3600
+        housing_loan_interest: 1000
+        housing_rent:
+          tier1: 1500
+          tier2: 1100
+          tier3: 800
+        elderly_support: 2000
+        elderly_support_share_max: 1000
+        serious_illness_threshold: 15000
+        serious_illness_max: 80000
+      - year: 2023
+        children_education: 2000
+        infant_care: 2000
+        continuing_education_degree: 400
+        continuing_education_certificate: 3600
+        housing_loan_interest: 1000
+        housing_rent:
+          tier1: 1500
+          tier2: 1100
+          tier3: 800
+        elderly_support: 3000
+        elderly_support_share_max: 1500
+        serious_illness_threshold: 15000
+        serious_illness_max: 80000
+
+    month_tax_rates:
+      - salary_min: 0
+        salary_max: 3000
+        rate: 3
+        deducted_amount: 0
+      - salary_min: 3000
+        salary_max: 12000
+        rate: 10
+        deducted_amount: 210
+      - salary_min: 12000
+        salary_max: 25000
+        rate: 20
+        deducted_amount: 1410
+      - salary_min: 25000
+        salary_max: 35000
+        rate: 25
+        deducted_amount: 2660
+      - salary_min: 35000
+        salary_max: 55000
+        rate: 30
+        deducted_amount: 4410
+      - salary_min: 55000
+        salary_max: 80000
+        rate: 35
+        deducted_amount: 7160
+      - salary_min: 80000
+        salary_max: 0
+        rate: 45
+        deducted_amount: 15160
+`
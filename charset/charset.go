@@ -0,0 +1,244 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package charset 读写 CSV 时的字符编码，兼容中文 Windows 下的 Excel
+// 用于 csv 输出、银行代发和个税申报的导出文件（--encoding），以及花名册的读取
+package charset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// Encoding 字符编码
+type Encoding string
+
+// 字符编码
+const (
+	UTF8    Encoding = "utf8"     // 不带 BOM 的 UTF-8
+	UTF8BOM Encoding = "utf8-bom" // 带 BOM 的 UTF-8，Excel 可以直接打开
+	GBK     Encoding = "gbk"      // 中文 Windows 的默认编码
+	GB18030 Encoding = "gb18030"  // 兼容 GBK，可以表示所有的 Unicode 字符
+)
+
+// Encodings 支持的字符编码
+var Encodings = []Encoding{UTF8, UTF8BOM, GBK, GB18030}
+
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+// ParseEncoding 解析字符编码，为空时使用 UTF-8
+func ParseEncoding(s string) (Encoding, error) {
+	switch strings.ToLower(strings.Replace(s, "_", "-", -1)) {
+	case "", "utf8", "utf-8":
+		return UTF8, nil
+	case "utf8-bom", "utf-8-bom":
+		return UTF8BOM, nil
+	case "gbk", "cp936":
+		return GBK, nil
+	case "gb18030":
+		return GB18030, nil
+	}
+	names := make([]string, len(Encodings))
+	for i, e := range Encodings {
+		names[i] = string(e)
+	}
+	return "", fmt.Errorf("不支持的字符编码: %s, 可选: %s", s, strings.Join(names, "|"))
+}
+
+func (p Encoding) encoding() encoding.Encoding {
+	switch p {
+	case GBK:
+		return simplifiedchinese.GBK
+	case GB18030:
+		return simplifiedchinese.GB18030
+	}
+	return nil
+}
+
+// NewWriter 按编码写入 w，需要调用 Close 写入缓存的内容，Close 不会关闭 w
+func NewWriter(w io.Writer, e Encoding) io.WriteCloser {
+	switch e {
+	case UTF8BOM:
+		return &bomWriter{w: w}
+	case GBK, GB18030:
+		return &encodeWriter{e: e, w: transform.NewWriter(w, e.encoding().NewEncoder())}
+	}
+	return nopCloser{w}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// bomWriter 在第一次写入前写入 BOM
+type bomWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (p *bomWriter) Write(b []byte) (int, error) {
+	if err := p.writeBOM(); err != nil {
+		return 0, err
+	}
+	return p.w.Write(b)
+}
+
+// Close 没有写入内容时也写入 BOM
+func (p *bomWriter) Close() error {
+	return p.writeBOM()
+}
+
+func (p *bomWriter) writeBOM() error {
+	if p.written {
+		return nil
+	}
+	p.written = true
+	_, err := p.w.Write(bom)
+	return err
+}
+
+// encodeWriter 转换编码，无法表示的字符返回明确的错误
+type encodeWriter struct {
+	e Encoding
+	w io.WriteCloser
+}
+
+func (p *encodeWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	return n, p.wrap(err, b[n:])
+}
+
+func (p *encodeWriter) Close() error {
+	return p.wrap(p.w.Close(), nil)
+}
+
+// errUnencodable 目标编码无法表示某个字符时的错误
+// x/text 的错误类型在 internal 包中，无法直接引用，用一个 GBK 无法表示的字符取得
+var _, errUnencodable = simplifiedchinese.GBK.NewEncoder().String("\U0001F600")
+
+func (p *encodeWriter) wrap(err error, rest []byte) error {
+	if err == nil || err != encoding.ErrInvalidUTF8 && err != errUnencodable {
+		return err
+	}
+	if r, _ := utf8.DecodeRune(rest); len(rest) > 0 && r != utf8.RuneError {
+		return fmt.Errorf("字符 %q 无法用 %s 编码，请改用 gb18030 或 utf8", r, p.e)
+	}
+	return fmt.Errorf("存在无法用 %s 编码的字符，请改用 gb18030 或 utf8", p.e)
+}
+
+// Decode 自动识别 UTF-8（可带 BOM）、GBK 或 GB18030 编码，转为 UTF-8
+// 无法识别时返回第一个无效字节所在的行和位置
+func Decode(data []byte) ([]byte, Encoding, error) {
+	if bytes.HasPrefix(data, bom) {
+		data = data[len(bom):]
+		if !utf8.Valid(data) {
+			return nil, "", invalidUTF8(data)
+		}
+		return data, UTF8BOM, nil
+	}
+	if utf8.Valid(data) {
+		return data, UTF8, nil
+	}
+
+	e, err := detectGB(data)
+	if err != nil {
+		return nil, "", err
+	}
+	decoded, err := e.encoding().NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("按 %s 解码失败: %v", e, err)
+	}
+	return decoded, e, nil
+}
+
+// NewReader 读取 r 的全部内容，自动识别编码后转为 UTF-8
+func NewReader(r io.Reader) (io.Reader, Encoding, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	decoded, e, err := Decode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes.NewReader(decoded), e, nil
+}
+
+// detectGB 按 GB18030 的字节结构检查，只有双字节字符时为 GBK
+// 单字节 00-7F；双字节 81-FE 40-7E|80-FE；四字节 81-FE 30-39 81-FE 30-39
+func detectGB(data []byte) (Encoding, error) {
+	e := GBK
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c < 0x80:
+			i++
+			continue
+		case c == 0x80 || c == 0xFF:
+			return "", invalidByte(data, i)
+		}
+		if i+1 >= len(data) {
+			return "", invalidByte(data, i)
+		}
+		c2 := data[i+1]
+		switch {
+		case c2 >= 0x40 && c2 <= 0x7E || c2 >= 0x80 && c2 <= 0xFE:
+			i += 2
+		case c2 >= 0x30 && c2 <= 0x39:
+			if i+3 >= len(data) || data[i+2] < 0x81 || data[i+2] > 0xFE || data[i+3] < 0x30 || data[i+3] > 0x39 {
+				return "", invalidByte(data, i)
+			}
+			e = GB18030
+			i += 4
+		default:
+			return "", invalidByte(data, i)
+		}
+	}
+	return e, nil
+}
+
+// ErrInvalidBytes 不是 UTF-8、GBK 或 GB18030 编码
+var ErrInvalidBytes = errors.New("无效的字节序列，文件不是 UTF-8、GBK 或 GB18030 编码")
+
+func invalidUTF8(data []byte) error {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size <= 1 {
+			return invalidByte(data, i)
+		}
+		i += size
+	}
+	return ErrInvalidBytes
+}
+
+// invalidByte 第 offset 个字节无效，行号从 1 开始
+func invalidByte(data []byte, offset int) error {
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return fmt.Errorf("第 %d 行第 %d 个字节 0x%02X: %w", line, column, data[offset], ErrInvalidBytes)
+}
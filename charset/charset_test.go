@@ -0,0 +1,78 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package charset
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func encode(t *testing.T, e Encoding, s string) ([]byte, error) {
+	t.Helper()
+	var b bytes.Buffer
+	w := NewWriter(&b, e)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	const text = "员工编号,姓名,月薪\n001,张三,30000\n"
+	for _, e := range Encodings {
+		data, err := encode(t, e, text)
+		if err != nil {
+			t.Errorf("%s: %v", e, err)
+			continue
+		}
+		decoded, detected, err := Decode(data)
+		if err != nil {
+			t.Errorf("%s: %v", e, err)
+			continue
+		}
+		if string(decoded) != text {
+			t.Errorf("%s: 解码后为 %q", e, decoded)
+		}
+		// 常用汉字的 GBK 和 GB18030 编码相同，识别为 GBK
+		if detected != e && !(e == GB18030 && detected == GBK) {
+			t.Errorf("%s: 识别为 %s", e, detected)
+		}
+	}
+}
+
+func TestUnencodable(t *testing.T) {
+	_, err := encode(t, GBK, "姓名,😀\n")
+	if err == nil || !strings.Contains(err.Error(), "无法用 gbk 编码") {
+		t.Errorf("gbk 写入 emoji 的错误为 %v", err)
+	}
+	if _, err := encode(t, GB18030, "姓名,😀\n"); err != nil {
+		t.Errorf("gb18030 写入 emoji: %v", err)
+	}
+}
+
+func TestInvalidBytes(t *testing.T) {
+	_, _, err := Decode([]byte("a,b\n\xff\xfe\xfd\n"))
+	if !errors.Is(err, ErrInvalidBytes) {
+		t.Errorf("错误为 %v, 应为 %v", err, ErrInvalidBytes)
+	}
+}
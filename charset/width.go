@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package charset
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Width 文本在终端中的显示宽度，中日韩文字及全角字符占两列
+func Width(s string) int {
+	width := 0
+	for _, r := range s {
+		if wide(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// PadRight 按显示宽度在右侧补足空格
+func PadRight(s string, width int) string {
+	if n := Width(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// PadLeft 按显示宽度在左侧补足空格，用于右对齐
+func PadLeft(s string, width int) string {
+	if n := Width(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
+
+// wide 东亚宽字符：中日韩文字、CJK 标点（、。「」）以及全角符号（（）：，）
+func wide(r rune) bool {
+	switch {
+	case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hangul, r),
+		unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+		return true
+	case r >= 0x3000 && r <= 0x303f: // CJK 符号和标点
+		return true
+	case r >= 0xff00 && r <= 0xff60, r >= 0xffe0 && r <= 0xffe6: // 全角 ASCII 及全角符号
+		return true
+	}
+	return false
+}
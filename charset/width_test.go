@@ -0,0 +1,46 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package charset
+
+import "testing"
+
+func TestWidth(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"abc 123", 7},
+		{"税前收入", 8},
+		{"offer（北京）", 13},
+		{"项目：金额，合计。", 18},
+		{"ＡＢ", 4},
+		{"かナ한", 6},
+	} {
+		if got := Width(c.in); got != c.want {
+			t.Errorf("Width(%q) = %d, 应为 %d", c.in, got, c.want)
+		}
+	}
+
+	if got := PadRight("（北京）", 10); got != "（北京）  " {
+		t.Errorf("PadRight = %q", got)
+	}
+	if got := PadLeft("abc", 5); got != "  abc" {
+		t.Errorf("PadLeft = %q", got)
+	}
+}
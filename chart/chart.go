@@ -0,0 +1,185 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package chart 生成独立的 SVG 折线图，不依赖外部资源
+package chart
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Chart 共用横轴的多个面板，自上而下排列
+type Chart struct {
+	Title  string
+	XLabel string
+	X      []float64
+	Panels []*Panel
+}
+
+// Panel 一个面板，面板内的折线共用纵轴
+type Panel struct {
+	YLabel string
+	Series []*Series
+}
+
+// Series 一条折线，Values 与 Chart.X 一一对应
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+const (
+	width       = 960
+	panelHeight = 300
+	marginLeft  = 90
+	marginRight = 160
+	marginTop   = 50
+	panelGap    = 60
+	marginBot   = 50
+	ticks       = 5
+)
+
+// colors 折线的颜色，按顺序循环使用
+var colors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b"}
+
+// Write 写入 SVG
+func (p *Chart) Write(w io.Writer) error {
+	if len(p.X) == 0 {
+		return fmt.Errorf("没有数据")
+	}
+	height := marginTop + len(p.Panels)*(panelHeight+panelGap) - panelGap + marginBot
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="28" font-size="16" text-anchor="middle">%s</text>`+"\n", width/2, escape(p.Title))
+
+	xMin, xMax := bounds([][]float64{p.X})
+	for i, panel := range p.Panels {
+		top := marginTop + i*(panelHeight+panelGap)
+		p.writePanel(&b, panel, top, xMin, xMax, i == len(p.Panels)-1)
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Chart) writePanel(b *strings.Builder, panel *Panel, top int, xMin, xMax float64, last bool) {
+	plotWidth := float64(width - marginLeft - marginRight)
+	bottom := top + panelHeight
+
+	var values [][]float64
+	for _, s := range panel.Series {
+		values = append(values, s.Values)
+	}
+	yMin, yMax := bounds(values)
+	yMin, yMax, yStep := niceRange(yMin, yMax)
+
+	x := func(v float64) float64 {
+		return marginLeft + (v-xMin)/(xMax-xMin)*plotWidth
+	}
+	y := func(v float64) float64 {
+		return float64(bottom) - (v-yMin)/(yMax-yMin)*panelHeight
+	}
+
+	// 网格和纵轴刻度
+	for v := yMin; v <= yMax+yStep/2; v += yStep {
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e0e0e0"/>`+"\n", marginLeft, y(v), width-marginRight, y(v))
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", marginLeft-6, y(v), label(v))
+	}
+	_, xMaxNice, xStep := niceRange(xMin, xMax)
+	for v := math.Ceil(xMin/xStep) * xStep; v <= xMaxNice && v <= xMax; v += xStep {
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#f0f0f0"/>`+"\n", x(v), top, x(v), bottom)
+		if last {
+			fmt.Fprintf(b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n", x(v), bottom+18, label(v))
+		}
+	}
+	fmt.Fprintf(b, `<rect x="%d" y="%d" width="%.0f" height="%d" fill="none" stroke="#333333"/>`+"\n", marginLeft, top, plotWidth, panelHeight)
+	fmt.Fprintf(b, `<text x="%d" y="%d" font-size="13">%s</text>`+"\n", marginLeft, top-8, escape(panel.YLabel))
+	if last {
+		fmt.Fprintf(b, `<text x="%.0f" y="%d" text-anchor="middle" font-size="13">%s</text>`+"\n",
+			marginLeft+plotWidth/2, bottom+40, escape(p.XLabel))
+	}
+
+	for i, s := range panel.Series {
+		color := colors[i%len(colors)]
+		var points []string
+		for j, v := range s.Values {
+			if j >= len(p.X) || math.IsNaN(v) {
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(p.X[j]), y(v)))
+		}
+		fmt.Fprintf(b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", color, strings.Join(points, " "))
+
+		legendY := top + 16 + i*20
+		fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="2"/>`+"\n",
+			width-marginRight+12, legendY, width-marginRight+32, legendY, color)
+		fmt.Fprintf(b, `<text x="%d" y="%d" dominant-baseline="middle">%s</text>`+"\n", width-marginRight+38, legendY, escape(s.Name))
+	}
+}
+
+// bounds 所有数据的最小值和最大值，相等时向两边扩展
+func bounds(values [][]float64) (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, vs := range values {
+		for _, v := range vs {
+			if math.IsNaN(v) {
+				continue
+			}
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+	}
+	if math.IsInf(min, 1) {
+		return 0, 1
+	}
+	if min == max {
+		return min - 1, max + 1
+	}
+	return min, max
+}
+
+// niceRange 扩展到整齐的刻度
+func niceRange(min, max float64) (float64, float64, float64) {
+	raw := (max - min) / ticks
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := magnitude
+	for _, m := range []float64{1, 2, 2.5, 5, 10} {
+		step = m * magnitude
+		if step >= raw {
+			break
+		}
+	}
+	return math.Floor(min/step) * step, math.Ceil(max/step) * step, step
+}
+
+// label 刻度文字，整数不带小数
+func label(v float64) string {
+	if math.Abs(v-math.Round(v)) < 1e-9 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
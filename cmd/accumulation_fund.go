@@ -37,30 +37,34 @@ var accumulationFundCmd = &cobra.Command{
 ./tax f
 
 	样例:
-	./tax --config="tax.yaml" f -c="personal.yaml"
+	./tax --config="tax.yaml" --date="2024-07" f -c="personal.yaml"
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("开始计算公积金")
-
-		i, err := handlers.NewAccumulationFundHandler(cfgFile)
-		if err != nil {
-			fmt.Println("读取配置出错")
-			return
-		}
 		var afPersonalInfo handlers.PersonalInfo
 
 		if err := config.NewSuffixReader().Read(accumulationFundConfig, &afPersonalInfo); err != nil {
 			log.Fatalln("读取配置失败", err)
 			return
 		}
+		if err := afPersonalInfo.Validate(); err != nil {
+			log.Fatalln("配置错误", err)
+		}
+
+		i, err := loadPolicyHandler(afPersonalInfo.Jurisdiction)
+		if err != nil {
+			fmt.Println("读取配置出错", err)
+			return
+		}
 
-		result, err := i.Calc(&afPersonalInfo)
+		result, err := i.AccumulationFundHandler.Calc(&afPersonalInfo)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 
-		result.Print()
+		if err := output("开始计算公积金", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
 	},
 }
 
@@ -70,4 +74,5 @@ func init() {
 	rootCmd.AddCommand(accumulationFundCmd)
 
 	accumulationFundCmd.Flags().StringVarP(&accumulationFundConfig, "subc", "c", "personal.yaml", "个人信息配置文件路径")
+	accumulationFundCmd.Flags().BoolVar(&explain, "explain", false, "输出每一步的公式、输入、结果及舍入方式")
 }
@@ -0,0 +1,104 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"log"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/ymhhh/tax/batch"
+)
+
+// batchCmd represents the batch command
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "按员工花名册批量计算工资个税",
+	Long: `
+读取 CSV 或 XLSX 格式的员工花名册，每行为一名员工一个月的工资，并发计算后按花名册顺序输出每名员工的结果和公司汇总
+出错的行会给出行号和原因，不影响其他员工的计算
+
+	表头（英文或中文均可，不区分顺序，无法识别的列会被忽略）：
+	employee_id(员工编号)  必填
+	month(月份)            必填，如 2024-01
+	name(姓名)、city(地区)、employer(任职单位)、residence(户口类型)、endowment(养老类型)
+	threshold(起征线)、salary(月薪)、subsidy_amount(补助)、deductible_amount(可抵扣金额)、extra_amount(额外工资)
+	accumulation_fund_base(公积金基数)、accumulation_fund_rate(公积金比例)、base_source、average_wage
+	bonus(年终奖)、bonus_method(奖金计税方式)、id_number(证件号码)、bank_account(银行账号)
+
+	--bank-file、--declaration-file 另外导出银行代发和个税扣缴申报的 csv 文件，编码同 --encoding
+
+./tax batch --input roster.csv
+
+	完整样例
+	./tax --city beijing batch --input roster.xlsx --workers 4 --output csv --out-file payroll.csv
+	./tax --encoding gbk batch --input roster.csv --bank-file bank.csv --declaration-file declaration.csv
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		taxes, err := loadTaxesHandler()
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		roster, err := batch.ReadRoster(batchInput)
+		if err != nil {
+			log.Fatalln("读取花名册失败", err)
+		}
+		// 花名册中的地区优先于 --city
+		for _, e := range roster.Employees {
+			if e.Salaries.Jurisdiction == "" {
+				e.Salaries.Jurisdiction = policyCity
+			}
+			if policyYear != 0 {
+				e.Salaries.Year = policyYear
+			}
+		}
+
+		result := batch.Run(taxes, roster, batchWorkers)
+		if err := output("开始批量计算", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+		if batchBankFile != "" {
+			if err := writeExport(batchBankFile, result.Bank()); err != nil {
+				log.Fatalln("导出银行代发文件失败", err)
+			}
+		}
+		if batchDeclarationFile != "" {
+			if err := writeExport(batchDeclarationFile, result.Declaration()); err != nil {
+				log.Fatalln("导出个税申报文件失败", err)
+			}
+		}
+	},
+}
+
+var (
+	batchInput           string
+	batchWorkers         int
+	batchBankFile        string
+	batchDeclarationFile string
+)
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().StringVarP(&batchInput, "input", "i", "", "员工花名册文件，.csv 或 .xlsx")
+	batchCmd.Flags().IntVar(&batchWorkers, "workers", runtime.NumCPU(), "并发计算的数量")
+	batchCmd.Flags().StringVar(&batchBankFile, "bank-file", "", "导出银行代发的 csv 文件")
+	batchCmd.Flags().StringVar(&batchDeclarationFile, "declaration-file", "", "导出个税扣缴申报的 csv 文件")
+	batchCmd.MarkFlagRequired("input")
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// bonusCmd represents the bonus command
+var bonusCmd = &cobra.Command{
+	Use:     "bonus",
+	Aliases: []string{"b"},
+	Short:   "计算全年一次性奖金",
+	Long: `
+分别按单独计税和并入综合所得计算全年一次性奖金的个税以及全年税后收入
+奖金在月工资配置文件的 bonus 中设置
+./tax b
+
+	完整样例
+	./tax --config="tax.yaml" b -c="salaries.yaml"
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		taxes, err := loadTaxesHandler()
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		ss, err := readSalaries(bonusConfig)
+		if err != nil {
+			log.Fatalln("读取配置失败", err)
+		}
+		applyPolicyFlags(ss)
+
+		result, err := taxes.CalcBonus(ss)
+		if err != nil {
+			log.Fatalln("计算出错", err)
+		}
+
+		if err := output("开始计算全年一次性奖金", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+var bonusConfig string
+
+func init() {
+	rootCmd.AddCommand(bonusCmd)
+
+	bonusCmd.Flags().StringVarP(&bonusConfig, "subc", "c", "salaries.yaml", "月工资配置文件")
+}
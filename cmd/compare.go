@@ -0,0 +1,89 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/ymhhh/tax/catalog"
+	"github.com/ymhhh/tax/handlers"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare offer.yaml...",
+	Short: "对比多个 offer 的全年收入",
+	Long: `
+每个 offer 为一份月工资配置，格式同 salaries.yaml，可以设置各自的地区、月薪、补贴、公积金比例和全年一次性奖金
+分别计算后并排列出全年的税前收入、个人及单位缴纳的社保和公积金、个税、税后现金，以及税后现金加公积金的合计
+offer 的名称为文件名；默认使用内置的各地区政策，指定 --config 时使用该配置文件
+./tax compare offerA.yaml offerB.yaml
+
+	完整样例
+	./tax --year 2024 compare beijing.yaml shanghai.yaml -o table
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		taxes, err := loadCompareHandler(cmd)
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		var names []string
+		var offers []*handlers.Salaries
+		for _, file := range args {
+			ss, err := readSalaries(file)
+			if err != nil {
+				log.Fatalln("读取配置失败", err)
+			}
+			// offer 中的地区优先于 --city
+			if ss.Jurisdiction == "" {
+				ss.Jurisdiction = policyCity
+			}
+			if policyYear != 0 {
+				ss.Year = policyYear
+			}
+			names = append(names, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+			offers = append(offers, ss)
+		}
+
+		result, err := taxes.CompareOffers(names, offers)
+		if err != nil {
+			log.Fatalln("计算出错", err)
+		}
+
+		if err := output("开始对比 offer", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+// loadCompareHandler offer 通常在不同的地区，未指定 --config 时使用内置政策
+func loadCompareHandler(cmd *cobra.Command) (*handlers.TaxesHandler, error) {
+	if cmd.Flags().Changed("config") {
+		return handlers.NewTaxesHandler(cfgFile)
+	}
+	return catalog.Load()
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+}
@@ -18,7 +18,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"fmt"
 	"log"
 
 	"github.com/go-trellis/config"
@@ -37,27 +36,31 @@ var insuranceCmd = &cobra.Command{
 	./tax i
 
 	样例:
-	./tax --config="tax.yaml" i -c="personal.yaml"
+	./tax --config="tax.yaml" --date="2024-07" i -c="personal.yaml"
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("开始计算社会保险")
-		i, err := handlers.NewInsurancesHandler(cfgFile)
-		if err != nil {
-			log.Fatalln("读取配置文件失败", err)
-		}
-
 		var insuranceInfo handlers.PersonalInfo
 		if err := config.NewSuffixReader().Read(insuranceConfig, &insuranceInfo); err != nil {
 			log.Fatalln("读取配置失败", err)
 			return
 		}
+		if err := insuranceInfo.Validate(); err != nil {
+			log.Fatalln("配置错误", err)
+		}
 
-		result, err := i.Calc(&insuranceInfo)
+		i, err := loadPolicyHandler(insuranceInfo.Jurisdiction)
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		result, err := i.InsurancesHandler.Calc(&insuranceInfo)
 		if err != nil {
 			log.Fatalln("计算出错", err)
 		}
 
-		result.Print()
+		if err := output("开始计算社会保险", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
 	},
 }
 
@@ -67,4 +70,5 @@ func init() {
 	rootCmd.AddCommand(insuranceCmd)
 
 	insuranceCmd.Flags().StringVarP(&insuranceConfig, "subc", "c", "personal.yaml", "个人信息配置文件")
+	insuranceCmd.Flags().BoolVar(&explain, "explain", false, "输出每一步的公式、输入、结果及舍入方式")
 }
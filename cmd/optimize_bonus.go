@@ -0,0 +1,74 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/ymhhh/tax/handlers"
+)
+
+// optimizeBonusCmd represents the optimize-bonus command
+var optimizeBonusCmd = &cobra.Command{
+	Use:     "optimize-bonus",
+	Aliases: []string{"ob"},
+	Short:   "优化年终奖与月薪的拆分",
+	Long: `
+在全年税前总包不变的情况下，搜索月薪与全年一次性奖金的拆分，以及奖金的计税方式，使全年个税最低
+并提示年终奖陷阱区间（多发一元奖金，多交的个税超过一元）
+./tax optimize-bonus
+
+	完整样例
+	./tax --config="tax.yaml" optimize-bonus -c="salaries.yaml" --step=500
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		taxes, err := loadTaxesHandler()
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		ss, err := readSalaries(optimizeBonusConfig)
+		if err != nil {
+			log.Fatalln("读取配置失败", err)
+		}
+		applyPolicyFlags(ss)
+
+		result, err := taxes.OptimizeBonus(context.Background(), ss, handlers.NewMoney(optimizeBonusStep))
+		if err != nil {
+			log.Fatalln("计算出错", err)
+		}
+
+		if err := output("开始优化年终奖拆分", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+var (
+	optimizeBonusConfig string
+	optimizeBonusStep   float64
+)
+
+func init() {
+	rootCmd.AddCommand(optimizeBonusCmd)
+
+	optimizeBonusCmd.Flags().StringVarP(&optimizeBonusConfig, "subc", "c", "salaries.yaml", "月工资配置文件")
+	optimizeBonusCmd.Flags().Float64Var(&optimizeBonusStep, "step", 1000, "奖金搜索步长")
+}
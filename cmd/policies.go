@@ -0,0 +1,118 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/ymhhh/tax/catalog"
+	"github.com/ymhhh/tax/handlers"
+)
+
+// policiesCmd represents the policies command
+var policiesCmd = &cobra.Command{
+	Use:     "policies",
+	Aliases: []string{"p"},
+	Short:   "查看内置的各地区政策",
+	Long: `
+查看程序内置的各地区五险一金政策，以及个税政策
+./tax policies list
+./tax policies show shanghai
+
+	样例:
+	./tax --year 2025 policies show shanghai
+`,
+}
+
+var policiesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出内置的地区及生效期间",
+	Run: func(cmd *cobra.Command, args []string) {
+		registry, err := catalog.Registry()
+		if err != nil {
+			log.Fatalln("读取内置政策失败", err)
+		}
+
+		if err := output("", registry.Summaries()); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+var policiesShowCmd = &cobra.Command{
+	Use:   "show [city]",
+	Short: "查看地区的政策参数",
+	Long: `
+查看地区的政策参数，指定 --date 时只显示该月适用的政策，指定 --year 时显示该年度各月适用的政策
+./tax policies show beijing
+./tax --date 2024-03 policies show beijing
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		registry, err := catalog.Registry()
+		if err != nil {
+			log.Fatalln("读取内置政策失败", err)
+		}
+
+		months, err := policyMonths()
+		if err != nil {
+			log.Fatalln("月份格式错误", err)
+		}
+
+		policies := registry.PoliciesOf(args[0])
+		if len(policies) == 0 {
+			log.Fatalln("未找到地区", args[0])
+		}
+		shown := &handlers.PolicyRegistry{}
+		for _, policy := range policies {
+			if containsAny(policy.Period, months) {
+				shown.Policies = append(shown.Policies, policy)
+			}
+		}
+		for _, policy := range registry.TaxPolicies {
+			if containsAny(policy.Period, months) {
+				shown.TaxPolicies = append(shown.TaxPolicies, policy)
+			}
+		}
+
+		if err := output("", shown); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+// containsAny 生效期间是否包含任一月份，months 为空时表示不限
+func containsAny(period handlers.Period, months []handlers.YearMonth) bool {
+	if len(months) == 0 {
+		return true
+	}
+	for _, month := range months {
+		if period.Contains(month) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(policiesCmd)
+
+	policiesCmd.AddCommand(policiesListCmd)
+	policiesCmd.AddCommand(policiesShowCmd)
+}
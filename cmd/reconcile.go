@@ -0,0 +1,69 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:     "reconcile",
+	Aliases: []string{"r"},
+	Short:   "年度汇算清缴",
+	Long: `
+按全年的工资薪金、劳务报酬、稿酬、特许权使用费计算综合所得的应纳税额，
+与已预扣预缴的税额比较，得出应退或应补的税额
+其他所得在月工资配置文件的 other_incomes 中设置
+./tax r
+
+	完整样例
+	./tax --config="tax.yaml" r -c="salaries.yaml"
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		taxes, err := loadTaxesHandler()
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		ss, err := readSalaries(reconcileConfig)
+		if err != nil {
+			log.Fatalln("读取配置失败", err)
+		}
+		applyPolicyFlags(ss)
+
+		result, err := taxes.Reconcile(ss)
+		if err != nil {
+			log.Fatalln("计算出错", err)
+		}
+
+		if err := output("开始年度汇算", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+var reconcileConfig string
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+
+	reconcileCmd.Flags().StringVarP(&reconcileConfig, "subc", "c", "salaries.yaml", "月工资配置文件")
+}
@@ -19,19 +19,35 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 
+	"github.com/go-trellis/config"
 	"github.com/spf13/cobra"
+	"github.com/ymhhh/tax/catalog"
+	"github.com/ymhhh/tax/charset"
+	"github.com/ymhhh/tax/handlers"
+	"github.com/ymhhh/tax/render"
 )
 
-var cfgFile string
+var (
+	cfgFile    string
+	policyDate string
+	policyCity string
+	policyYear int
+
+	outputFormat string
+	outFile      string
+	csvEncoding  string
+	explain      bool
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "tax",
 	Short: "计算中国的五险一金，以及个税",
 	Long: `
-通过设置五险一金的基本参数来计算五险一金，tax.yaml中目前记录的配置是2019-2020年北京的个数基数
+通过设置五险一金的基本参数来计算五险一金，tax.yaml中按地区和生效期间登记了北京的五险一金基数，以及个税政策
 再通过个人的缴纳基数来计算出，每个月应该缴纳的五险一金费用，以及个人所得税
 
 	举例：
@@ -41,7 +57,55 @@ var rootCmd = &cobra.Command{
 	./tax i --help
 	3. 计算个税
 	./tax t --help
+	4. 计算全年一次性奖金
+	./tax b --help
+	5. 优化年终奖与月薪的拆分
+	./tax optimize-bonus --help
+	6. 年度汇算清缴
+	./tax r --help
+	7. 查看内置的各地区政策
+	./tax policies list
+	8. 查看展开后的月工资计划
+	./tax schedule --help
+	9. 启动 HTTP JSON 接口
+	./tax serve --help
+	10. 按员工花名册批量计算
+	./tax batch --help
+	11. 税后反推税前月薪
+	./tax solve --help
+	12. 对比多个 offer 的全年收入
+	./tax compare --help
+	13. 按月薪、公积金比例或奖金扫描全年个税
+	./tax sweep --help
+	14. 平滑每月的税后收入
+	./tax smooth --help
+
+	使用内置的地区政策代替配置文件：
+	./tax --city shanghai --year 2025 i
+
+	输出为其他格式，或写入文件：
+	./tax --output json t
+	./tax --output csv --out-file taxes.csv t
+	./tax --output csv --encoding gbk --out-file taxes.csv t
+	./tax --output xlsx --out-file taxes.xlsx t
 `,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		format, err := render.ParseFormat(outputFormat)
+		if err != nil {
+			return err
+		}
+		encoding, err := charset.ParseEncoding(csvEncoding)
+		if err != nil {
+			return err
+		}
+		if encoding != charset.UTF8 && format != render.FormatCSV && !writesExports(cmd) {
+			return fmt.Errorf("--encoding 只用于 csv 格式的输出和导出文件")
+		}
+		if format == render.FormatXLSX && outFile == "" {
+			return fmt.Errorf("xlsx 格式需要用 --out-file 指定输出文件")
+		}
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -58,4 +122,163 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "tax.yaml", "配置文件路径 (默认: tax.yaml)")
+	rootCmd.PersistentFlags().StringVar(&policyDate, "date", "", "计算月份，格式 2006-01，用于选择适用的政策 (默认: 最新政策)")
+	rootCmd.PersistentFlags().StringVar(&policyCity, "city", "", "使用内置的地区政策，如 beijing、shanghai，设置后不再读取配置文件")
+	rootCmd.PersistentFlags().IntVar(&policyYear, "year", 0, "计算年度，未设置 --date 时 f、i 按该年1月的政策计算")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "输出格式: text|json|yaml|csv|markdown|table|xlsx|svg")
+	rootCmd.PersistentFlags().StringVar(&outFile, "out-file", "", "将结果写入文件 (默认: 输出到终端)")
+	rootCmd.PersistentFlags().StringVar(&csvEncoding, "encoding", "utf8", "csv 输出及导出文件的字符编码: utf8|utf8-bom|gbk|gb18030，读取 csv 时自动识别")
+}
+
+// loadTaxesHandler 设置了 --city 时使用内置的政策，否则读取配置文件
+func loadTaxesHandler() (*handlers.TaxesHandler, error) {
+	if policyCity != "" {
+		return catalog.Load()
+	}
+	return handlers.NewTaxesHandler(cfgFile)
+}
+
+// policyMonth --date 或 --year 指定的月份，都未设置时为零值，表示最新政策
+func policyMonth() (handlers.YearMonth, error) {
+	if policyDate == "" && policyYear != 0 {
+		return handlers.YearMonth{Year: policyYear, Month: 1}, nil
+	}
+	return handlers.ParseYearMonth(policyDate)
+}
+
+// policyMonths --date 指定的月份，或 --year 指定年度的每个月，都未设置时为空，表示最新政策
+func policyMonths() ([]handlers.YearMonth, error) {
+	if policyDate == "" && policyYear != 0 {
+		months := make([]handlers.YearMonth, 12)
+		for i := range months {
+			months[i] = handlers.YearMonth{Year: policyYear, Month: i + 1}
+		}
+		return months, nil
+	}
+	month, err := handlers.ParseYearMonth(policyDate)
+	if err != nil || month.IsZero() {
+		return nil, err
+	}
+	return []handlers.YearMonth{month}, nil
+}
+
+// loadPolicyHandler 读取政策，并选出地区在指定月份适用的政策
+// 只设置 --year 时逐月查找该年度的政策，有月份没有政策时返回该月份的错误
+func loadPolicyHandler(jurisdiction string) (*handlers.TaxesHandler, error) {
+	month, err := policyMonth()
+	if err != nil {
+		return nil, err
+	}
+	months, err := policyMonths()
+	if err != nil {
+		return nil, err
+	}
+
+	taxes, err := loadTaxesHandler()
+	if err != nil {
+		return nil, err
+	}
+
+	if policyCity != "" {
+		jurisdiction = policyCity
+	}
+	for _, m := range months {
+		if _, err := taxes.Handler(jurisdiction, m); err != nil {
+			return nil, err
+		}
+	}
+	return taxes.Handler(jurisdiction, month)
+}
+
+// readSalaries 读取月工资配置并校验，配置错误时尽早给出明确的提示
+func readSalaries(file string) (*handlers.Salaries, error) {
+	ss := &handlers.Salaries{}
+	if err := config.NewSuffixReader().Read(file, ss); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if err := ss.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return ss, nil
+}
+
+// applyPolicyFlags 用 --city、--year 覆盖月工资配置中的地区和年度
+func applyPolicyFlags(ss *handlers.Salaries) {
+	if policyCity != "" {
+		ss.PersonalInfo.Jurisdiction = policyCity
+	}
+	if policyYear != 0 {
+		ss.Year = policyYear
+	}
+}
+
+// output 按 --output 的格式输出结果，设置了 --out-file 时写入文件
+// 文本格式时先在终端打印 title，设置了 --explain 时输出计算过程
+func output(title string, v interface{}) error {
+	format, err := render.ParseFormat(outputFormat)
+	if err != nil {
+		return err
+	}
+	if explainer, ok := v.(handlers.Explainer); ok && explain {
+		v = explainer.Explain()
+	}
+	if format == render.FormatText && title != "" {
+		fmt.Println(title)
+	}
+
+	if outFile == "" {
+		return renderEncoded(os.Stdout, format, v)
+	}
+
+	f, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	if err := renderEncoded(f, format, v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportFlags 导出 csv 文件的参数
+var exportFlags = []string{"bank-file", "declaration-file"}
+
+// writesExports 命令是否设置了导出 csv 文件的参数
+func writesExports(cmd *cobra.Command) bool {
+	for _, name := range exportFlags {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// writeExport 将导出文件按 --encoding 写为 csv
+func writeExport(file string, v interface{}) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := renderEncoded(f, render.FormatCSV, v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// renderEncoded csv 格式时按 --encoding 转换编码
+func renderEncoded(w io.Writer, format render.Format, v interface{}) error {
+	if format != render.FormatCSV {
+		return render.Render(w, format, v)
+	}
+	encoding, err := charset.ParseEncoding(csvEncoding)
+	if err != nil {
+		return err
+	}
+	ew := charset.NewWriter(w, encoding)
+	if err := render.Render(ew, format, v); err != nil {
+		return err
+	}
+	return ew.Close()
 }
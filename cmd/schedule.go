@@ -0,0 +1,62 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// scheduleCmd represents the schedule command
+var scheduleCmd = &cobra.Command{
+	Use:     "schedule",
+	Aliases: []string{"s"},
+	Short:   "查看展开后的月工资计划",
+	Long: `
+按月工资配置中的 schedule 规则展开为逐月的月工资，并列出每月执行的调整
+./tax schedule
+
+	完整样例
+	./tax schedule -c="salaries.yaml"
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ss, err := readSalaries(scheduleConfig)
+		if err != nil {
+			log.Fatalln("读取配置失败", err)
+		}
+		applyPolicyFlags(ss)
+
+		schedule, err := ss.ExpandSchedule()
+		if err != nil {
+			log.Fatalln("展开月工资计划出错", err)
+		}
+
+		if err := output("", schedule); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+var scheduleConfig string
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+
+	scheduleCmd.Flags().StringVarP(&scheduleConfig, "subc", "c", "salaries.yaml", "月工资配置文件")
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ymhhh/tax/server"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "启动本地的 HTTP JSON 接口",
+	Long: `
+启动 HTTP JSON 接口，请求体和响应体与配置文件、--output json 的结构相同
+	GET  /healthz
+	GET  /api/v1/policies
+	POST /api/v1/accumulation-fund  个人信息，同 personal.yaml
+	POST /api/v1/insurances         个人信息，同 personal.yaml
+	POST /api/v1/taxes              月工资配置，同 salaries.yaml
+	POST /api/v1/bonus              月工资配置，同 salaries.yaml
+	POST /api/v1/bonus/optimize     月工资配置，同 salaries.yaml，参数 step 为奖金搜索步长
+	POST /api/v1/reconcile          月工资配置，同 salaries.yaml
+
+	每个请求可以用 city、date、year 参数选择适用的政策，出错时返回 {"error": {"code": "...", "message": "..."}}
+
+./tax serve --listen :8080
+
+	完整样例
+	./tax --city beijing serve --listen 127.0.0.1:8080 --timeout 10s
+	curl -X POST 'http://127.0.0.1:8080/api/v1/insurances?date=2024-08' -d '{"salary": 30000}'
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		taxes, err := loadTaxesHandler()
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		srv := &http.Server{
+			Addr:              serveListen,
+			Handler:           server.New(taxes, serveTimeout),
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       serveTimeout + 5*time.Second,
+			WriteTimeout:      serveTimeout + 5*time.Second,
+			IdleTimeout:       time.Minute,
+		}
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- srv.ListenAndServe()
+		}()
+		log.Println("开始监听", serveListen)
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		select {
+		case err := <-errs:
+			log.Fatalln("启动服务失败", err)
+		case <-stop:
+		}
+
+		// 停止接收新的请求，等待处理中的请求完成
+		ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Fatalln("关闭服务出错", err)
+		}
+		log.Println("服务已关闭")
+	},
+}
+
+var (
+	serveListen          string
+	serveTimeout         time.Duration
+	serveShutdownTimeout time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "监听地址")
+	serveCmd.Flags().DurationVar(&serveTimeout, "timeout", 10*time.Second, "单个请求的计算超时")
+	serveCmd.Flags().DurationVar(&serveShutdownTimeout, "shutdown-timeout", 10*time.Second, "关闭服务时等待处理中请求的时间")
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// smoothCmd represents the smooth command
+var smoothCmd = &cobra.Command{
+	Use:   "smooth",
+	Short: "平滑每月的税后收入",
+	Long: `
+累计预扣法下，即使税前月薪不变，税后收入也会随着税率档的提高逐月下降
+在全年个税不增加的前提下，调整全年一次性奖金的发放月份、补贴的发放节奏（全年合计不变），
+以及公积金年度调整时的比例，使每月的税后收入尽量平稳，并与调整前逐月对比
+./tax smooth
+
+	完整样例
+	./tax --config="tax.yaml" smooth -c="salaries.yaml" --adjust-month 7
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		taxes, err := loadTaxesHandler()
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		ss, err := readSalaries(smoothConfig)
+		if err != nil {
+			log.Fatalln("读取配置失败", err)
+		}
+		applyPolicyFlags(ss)
+
+		result, err := taxes.SmoothNetPay(ss, smoothAdjustMonth)
+		if err != nil {
+			log.Fatalln("计算出错", err)
+		}
+
+		if err := output("开始平滑税后收入", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+var (
+	smoothConfig      string
+	smoothAdjustMonth int
+)
+
+func init() {
+	rootCmd.AddCommand(smoothCmd)
+
+	smoothCmd.Flags().StringVarP(&smoothConfig, "subc", "c", "salaries.yaml", "月工资配置文件")
+	smoothCmd.Flags().IntVar(&smoothAdjustMonth, "adjust-month", 7, "公积金年度调整的月份，公积金比例只能从该月起调整")
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/ymhhh/tax/handlers"
+)
+
+// solveCmd represents the solve command
+var solveCmd = &cobra.Command{
+	Use:   "solve",
+	Short: "税后反推税前月薪",
+	Long: `
+按月工资配置中的社保、公积金比例、专项附加扣除等，反推税后收入达到 --net 所需的税前月薪
+计算时每月月薪都替换为同一个值，不计全年一次性奖金；--month 指定按某月的税后收入，默认按全年的月平均税后收入
+累计预扣法下税后收入可能随税前增加而减少，此时会列出所有的解
+./tax solve --net 20000
+
+	完整样例
+	./tax --config="tax.yaml" solve -c="salaries.yaml" --net 20000 --month 3 --fund-rate 12
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		taxes, err := loadTaxesHandler()
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		ss, err := readSalaries(solveConfig)
+		if err != nil {
+			log.Fatalln("读取配置失败", err)
+		}
+		applyPolicyFlags(ss)
+
+		if cmd.Flags().Changed("fund-rate") {
+			ss, err = ss.Expand()
+			if err != nil {
+				log.Fatalln("读取配置失败", err)
+			}
+			for i := range ss.MonthlySalaries {
+				ss.MonthlySalaries[i].AccumulationFundRate = solveFundRate
+			}
+		}
+
+		result, err := taxes.SolveNet(ss, handlers.NewMoney(solveNet), solveMonth)
+		if err != nil {
+			log.Fatalln("计算出错", err)
+		}
+
+		if err := output("开始反推税前月薪", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+var (
+	solveConfig   string
+	solveNet      float64
+	solveMonth    int
+	solveFundRate float64
+)
+
+func init() {
+	rootCmd.AddCommand(solveCmd)
+
+	solveCmd.Flags().StringVarP(&solveConfig, "subc", "c", "salaries.yaml", "月工资配置文件")
+	solveCmd.Flags().Float64Var(&solveNet, "net", 0, "目标税后收入")
+	solveCmd.Flags().IntVar(&solveMonth, "month", 0, "按该月的税后收入反推，0 为全年的月平均税后收入")
+	solveCmd.Flags().Float64Var(&solveFundRate, "fund-rate", 0, "公积金比例，不设置时使用月工资配置中的比例")
+	solveCmd.MarkFlagRequired("net")
+}
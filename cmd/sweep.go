@@ -0,0 +1,112 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/ymhhh/tax/handlers"
+)
+
+// sweepCmd represents the sweep command
+var sweepCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "按月薪、公积金比例或奖金扫描全年个税",
+	Long: `
+以月工资配置为基础，按 from:to:step 的范围逐个替换月薪、公积金比例或全年一次性奖金，计算全年的实际税率、边际税率、税后收入和用工成本
+用于观察社保基数上下限、个税税率级距带来的拐点；--output csv 输出表格，--output svg 输出折线图
+./tax sweep --salary 10000:80000:1000
+
+	完整样例
+	./tax --config="tax.yaml" sweep -c="salaries.yaml" --salary 10000:80000:1000 -o svg --out-file sweep.svg
+	./tax sweep --fund-rate 5:12:1 -o csv
+	./tax sweep --bonus 0:200000:5000
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		param, value, err := sweepParam(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		r, err := handlers.ParseSweepRange(value)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		taxes, err := loadTaxesHandler()
+		if err != nil {
+			log.Fatalln("读取配置文件失败", err)
+		}
+
+		ss, err := readSalaries(sweepConfig)
+		if err != nil {
+			log.Fatalln("读取配置失败", err)
+		}
+		applyPolicyFlags(ss)
+
+		result, err := taxes.Sweep(ss, param, r)
+		if err != nil {
+			log.Fatalln("计算出错", err)
+		}
+
+		if err := output("开始扫描", result); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
+	},
+}
+
+var (
+	sweepConfig   string
+	sweepSalary   string
+	sweepFundRate string
+	sweepBonus    string
+)
+
+// sweepParam 扫描的参数，--salary、--fund-rate、--bonus 只能设置一个
+func sweepParam(cmd *cobra.Command) (handlers.SweepParam, string, error) {
+	var params []handlers.SweepParam
+	var value string
+	for _, f := range []struct {
+		flag  string
+		param handlers.SweepParam
+		value string
+	}{
+		{"salary", handlers.SweepSalary, sweepSalary},
+		{"fund-rate", handlers.SweepFundRate, sweepFundRate},
+		{"bonus", handlers.SweepBonus, sweepBonus},
+	} {
+		if cmd.Flags().Changed(f.flag) {
+			params = append(params, f.param)
+			value = f.value
+		}
+	}
+	if len(params) != 1 {
+		return "", "", fmt.Errorf("--salary、--fund-rate、--bonus 需要且只能设置一个")
+	}
+	return params[0], value, nil
+}
+
+func init() {
+	rootCmd.AddCommand(sweepCmd)
+
+	sweepCmd.Flags().StringVarP(&sweepConfig, "subc", "c", "salaries.yaml", "月工资配置文件")
+	sweepCmd.Flags().StringVar(&sweepSalary, "salary", "", "月薪的范围，格式 from:to:step")
+	sweepCmd.Flags().StringVar(&sweepFundRate, "fund-rate", "", "公积金比例的范围，格式 from:to:step")
+	sweepCmd.Flags().StringVar(&sweepBonus, "bonus", "", "全年一次性奖金的范围，格式 from:to:step")
+}
@@ -18,11 +18,9 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"fmt"
+	"log"
 
-	"github.com/go-trellis/config"
 	"github.com/spf13/cobra"
-	"github.com/ymhhh/tax/handlers"
 )
 
 var salariesConfig string
@@ -40,25 +38,25 @@ var taxCmd = &cobra.Command{
 	./tax --config="tax.yaml" t -c="salaries.yaml"
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("开始计算个税情况")
-
-		taxes, err := handlers.NewTaxesHandler(cfgFile)
+		taxes, err := loadTaxesHandler()
 		if err != nil {
-			panic(err)
+			log.Fatalln("读取配置文件失败", err)
 		}
 
-		ss := &handlers.Salaries{}
-
-		if err := config.NewSuffixReader().Read(subCfgFile, ss); err != nil {
-			panic(err)
+		ss, err := readSalaries(subCfgFile)
+		if err != nil {
+			log.Fatalln("读取配置失败", err)
 		}
+		applyPolicyFlags(ss)
 
 		data, err := taxes.Calc(ss)
 		if err != nil {
-			panic(err)
+			log.Fatalln("计算失败", err)
 		}
 
-		data.Print()
+		if err := output("开始计算个税情况", data); err != nil {
+			log.Fatalln("输出结果失败", err)
+		}
 	},
 }
 
@@ -68,4 +66,5 @@ func init() {
 	rootCmd.AddCommand(taxCmd)
 
 	taxCmd.Flags().StringVarP(&subCfgFile, "subc", "c", "salaries.yaml", "月工资配置文件")
+	taxCmd.Flags().BoolVar(&explain, "explain", false, "输出每一步的公式、输入、结果及舍入方式")
 }
@@ -6,4 +6,6 @@ require (
 	github.com/go-trellis/common v1.7.0 // indirect
 	github.com/go-trellis/config v1.4.1
 	github.com/spf13/cobra v1.0.0
+	golang.org/x/text v0.3.2
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -19,6 +19,8 @@ package handlers
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/go-trellis/config"
 )
@@ -32,16 +34,21 @@ type AccumulationFundHandler struct {
 type CalcAccumulationFund struct {
 	AccumulationFundBase `yaml:"accumulation_fund" json:"accumulation_fund"`
 
-	Salary float64 `yaml:"salary" json:"salary"`
-	Base   float64 `yaml:"base" json:"base"`
+	Salary Money   `yaml:"salary" json:"salary"`
+	Base   Money   `yaml:"base" json:"base"`
 	Rate   float64 `yaml:"rate" json:"rate"`
 
-	CompanyFund    float64 `yaml:"company_fund" json:"company_fund"`
-	MinCompanyFund float64 `yaml:"min_company_fund" json:"min_company_fund"`
-	MaxCompanyFund float64 `yaml:"max_company_fund" json:"max_company_fund"`
-	PrivateFund    float64 `yaml:"private_fund" json:"private_fund"`
-	MinPrivateFund float64 `yaml:"min_private_fund" json:"min_private_fund"`
-	MaxPrivateFund float64 `yaml:"max_private_fund" json:"max_private_fund"`
+	BaseResult BaseResult `yaml:"base_result" json:"base_result"`
+
+	CompanyFund    Money `yaml:"company_fund" json:"company_fund"`
+	MinCompanyFund Money `yaml:"min_company_fund" json:"min_company_fund"`
+	MaxCompanyFund Money `yaml:"max_company_fund" json:"max_company_fund"`
+	PrivateFund    Money `yaml:"private_fund" json:"private_fund"`
+	MinPrivateFund Money `yaml:"min_private_fund" json:"min_private_fund"`
+	MaxPrivateFund Money `yaml:"max_private_fund" json:"max_private_fund"`
+
+	// 计算过程
+	Explanation *Step `yaml:"-" json:"-"`
 }
 
 // NewAccumulationFundHandler 生成公积金对象
@@ -60,14 +67,36 @@ const (
 
 // Print 打印信息
 func (p *CalcAccumulationFund) Print() {
-	fmt.Println(fmt.Sprintf(printFundInfor, "公积金",
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *CalcAccumulationFund) Fprint(w io.Writer) {
+	line := fmt.Sprintf(printFundInfor, "公积金",
 		p.Salary,
 		p.AccumulationFundBase.MinBase, p.AccumulationFundBase.MaxBase,
 		p.AccumulationFundBase.MinRate, p.AccumulationFundBase.MaxRate,
 		p.MinCompanyFund, p.MaxCompanyFund,
 		p.MinPrivateFund, p.MaxPrivateFund,
 		p.Base, p.Rate, p.CompanyFund, p.PrivateFund,
-	))
+	) + p.BaseResult.Note()
+	fmt.Fprintln(w, line)
+}
+
+// Explain 计算过程
+func (p *CalcAccumulationFund) Explain() *Step {
+	return p.Explanation
+}
+
+// Tables 转为表格
+func (p *CalcAccumulationFund) Tables() []*Table {
+	t := &Table{
+		Title:   "公积金",
+		Headers: []string{"项目", "月收入", "实际基数", "缴纳比例", "单位缴纳", "个人缴纳"},
+	}
+	t.AddRow("公积金", p.Salary.String(), p.Base.String(), rateCell(p.Rate),
+		p.CompanyFund.String(), p.PrivateFund.String())
+	return []*Table{t}
 }
 
 // Calc 计算
@@ -79,26 +108,37 @@ func (p *AccumulationFundHandler) Calc(info *PersonalInfo) (*CalcAccumulationFun
 		Rate:   info.AccumulationFundRate,
 	}
 
-	if result.Salary > p.AccumulationFundBase.MaxBase {
-		result.Base = p.AccumulationFundBase.MaxBase
-	} else if result.Salary < p.AccumulationFundBase.MinBase {
-		return result, fmt.Errorf("小于最小基数: %0.f", p.AccumulationFundBase.MinBase)
-	} else {
-		result.Base = info.Salary
-	}
+	result.BaseResult = ClampBase(info.DeriveBase(info.AccumulationFundBase),
+		p.AccumulationFundBase.MinBase, p.AccumulationFundBase.MaxBase)
+	result.Base = result.BaseResult.Actual
 
 	if result.Rate > p.AccumulationFundBase.MaxRate || result.Rate < p.AccumulationFundBase.MinRate {
 		return nil, fmt.Errorf("比例需在 %0.f 和 %0.f 之间",
 			p.AccumulationFundBase.MinRate, p.AccumulationFundBase.MaxRate)
 	}
 
-	result.CompanyFund = Decimal(result.Base*result.Rate/100.0, 0)
+	result.CompanyFund = p.Amount(result.Base, result.Rate)
 	result.PrivateFund = result.CompanyFund
 
-	result.MaxCompanyFund = Decimal(result.MaxBase*result.MaxRate/100.0, 0)
-	result.MinCompanyFund = Decimal(result.MinBase*result.MinRate/100.0, 0)
+	result.MaxCompanyFund = p.Amount(result.MaxBase, result.MaxRate)
+	result.MinCompanyFund = p.Amount(result.MinBase, result.MinRate)
 	result.MaxPrivateFund = result.MaxCompanyFund
 	result.MinPrivateFund = result.MinCompanyFund
 
+	rate := NewStep("缴纳比例", "申报比例").Rate("申报比例", result.Rate).
+		Rate("最低比例", p.AccumulationFundBase.MinRate).Rate("最高比例", p.AccumulationFundBase.MaxRate)
+	rate.Result = percent(result.Rate)
+	rate.Note = "需在最低比例和最高比例之间"
+
+	rounding := p.Rounding.Or(RoundHalfUpYuan)
+	result.Explanation = NewStep("公积金", "").Add(
+		explainBase("缴费基数", &info.SalaryBase, info.AccumulationFundBase, result.BaseResult,
+			p.AccumulationFundBase.MinBase, p.AccumulationFundBase.MaxBase),
+		rate,
+		explainAmount("单位缴纳", result.Base, result.Rate, 0, rounding, result.CompanyFund),
+		explainAmount("个人缴纳", result.Base, result.Rate, 0, rounding, result.PrivateFund),
+	)
+	result.Explanation.Result = fmt.Sprintf("单位 %s, 个人 %s", result.CompanyFund, result.PrivateFund)
+
 	return result, nil
 }
@@ -19,44 +19,91 @@ package handlers
 
 import (
 	"fmt"
-	"strconv"
 )
 
-// InsurancesBase 社保信息
-type InsurancesBase struct {
-	// 职工基本养老
-	WorkersEndowment Base `yaml:"workers_endowment" json:"workers_endowment"`
-	// 机关基本养老
-	OfficeEndowment Base `yaml:"office_endowment" json:"office_endowment"`
-	// 基本医保
-	Medical Base `yaml:"medical" json:"medical"`
-	// 非农失业
-	NonAgriculturalUnemployment Base `yaml:"non_agricultural_unemployment" json:"non_agricultural_unemployment"`
-	// 农业失业
-	AgriculturalUnemployment Base `yaml:"agricultural_unemployment" json:"agricultural_unemployment"`
-	// 工伤
-	EmploymentInjury Base `yaml:"employment_injury" json:"employment_injury"`
-	// 生育
-	Birth Base `yaml:"birth" json:"birth"`
-	// 大病医保
-	SeriousMedical Base `yaml:"serious_medical" json:"serious_medical"`
-}
-
 // Base 基础信息
 type Base struct {
-	MinBase      float64 `yaml:"min_base" json:"min_base"`
-	MaxBase      float64 `yaml:"max_base" json:"max_base"`
+	MinBase      Money   `yaml:"min_base" json:"min_base"`
+	MaxBase      Money   `yaml:"max_base" json:"max_base"`
 	PrivateRate  float64 `yaml:"private_rate" json:"private_rate"`
 	CompanyRate  float64 `yaml:"company_rate" json:"company_rate"`
-	ExtraPayment float64 `yaml:"extra_payment" json:"extra_payment"`
+	ExtraPayment Money   `yaml:"extra_payment" json:"extra_payment"` // 个人每月固定缴纳金额
+
+	CompanyExtraPayment Money `yaml:"company_extra_payment" json:"company_extra_payment"` // 单位每月固定缴纳金额
+
+	// 缴费金额的舍入方式，默认四舍五入到分
+	Rounding RoundingMode `yaml:"rounding" json:"rounding"`
+}
+
+// Amount 按比例计算缴费金额
+func (p Base) Amount(base Money, rate float64) Money {
+	return base.MulRate(rate, p.Rounding.Or(RoundHalfUp))
+}
+
+// BaseAdjustment 缴费基数的调整方式
+type BaseAdjustment string
+
+// 缴费基数的调整方式
+const (
+	// 未调整
+	BaseUnchanged BaseAdjustment = ""
+	// 低于下限，按下限缴纳
+	BaseRaisedToFloor BaseAdjustment = "floor"
+	// 高于上限，按上限缴纳
+	BaseCappedAtCeiling BaseAdjustment = "ceiling"
+)
+
+// BaseResult 实际缴费基数
+type BaseResult struct {
+	Declared   Money          `yaml:"declared" json:"declared"`
+	Actual     Money          `yaml:"actual" json:"actual"`
+	Adjustment BaseAdjustment `yaml:"adjustment" json:"adjustment"`
+}
+
+// Note 基数调整说明
+func (p BaseResult) Note() string {
+	switch p.Adjustment {
+	case BaseRaisedToFloor:
+		return fmt.Sprintf(", 申报基数: %.2f(低于下限，按下限缴纳)", p.Declared)
+	case BaseCappedAtCeiling:
+		return fmt.Sprintf(", 申报基数: %.2f(高于上限，按上限缴纳)", p.Declared)
+	default:
+		return ""
+	}
+}
+
+// ClampBase 将基数限制在上下限之间，上下限为 0 时表示不限制
+func ClampBase(declared, minBase, maxBase Money) BaseResult {
+	result := BaseResult{Declared: declared, Actual: declared}
+	switch {
+	case maxBase > 0 && declared > maxBase:
+		result.Actual = maxBase
+		result.Adjustment = BaseCappedAtCeiling
+	case minBase > 0 && declared < minBase:
+		result.Actual = minBase
+		result.Adjustment = BaseRaisedToFloor
+	}
+	return result
+}
+
+// Clamp 将基数限制在该险种的上下限之间
+func (p Base) Clamp(declared Money) BaseResult {
+	return ClampBase(declared, p.MinBase, p.MaxBase)
 }
 
 // AccumulationFundBase 公积金基数
 type AccumulationFundBase struct {
-	MinBase float64 `yaml:"min_base" json:"min_base"`
-	MaxBase float64 `yaml:"max_base" json:"max_base"`
+	MinBase Money   `yaml:"min_base" json:"min_base"`
+	MaxBase Money   `yaml:"max_base" json:"max_base"`
 	MinRate float64 `yaml:"min_rate" json:"min_rate"`
 	MaxRate float64 `yaml:"max_rate" json:"max_rate"`
+	// 缴存金额的舍入方式，默认四舍五入到元
+	Rounding RoundingMode `yaml:"rounding" json:"rounding"`
+}
+
+// Amount 按比例计算缴存金额
+func (p AccumulationFundBase) Amount(base Money, rate float64) Money {
+	return base.MulRate(rate, p.Rounding.Or(RoundHalfUpYuan))
 }
 
 // ResidenceType 定义户口类型
@@ -83,50 +130,173 @@ const (
 
 // PersonalInfo 个人基本信息
 type PersonalInfo struct {
+	// 参保地区，为空时使用默认地区
+	Jurisdiction string `yaml:"jurisdiction" json:"jurisdiction"`
+
 	Residence ResidenceType `yaml:"residence" json:"residence"`
 	Endowment EndowmentType `yaml:"endowment" json:"endowment"`
 
+	// 专项附加扣除
+	SpecialDeductions *SpecialDeductions `yaml:"special_deductions" json:"special_deductions"`
+
 	SalaryBase `yaml:",inline" json:",inline"`
 }
 
+// BaseSource 未申报缴费基数时，推导基数的来源
+type BaseSource string
+
+// 缴费基数来源
+const (
+	// 按当月薪水（默认）
+	BaseSourceSalary BaseSource = "salary"
+	// 按上年度月平均工资
+	BaseSourceAverageWage BaseSource = "average_wage"
+)
+
 // SalaryBase 基本薪水信息
 type SalaryBase struct {
-	Threshold        float64 `yaml:"threshold" json:"threshold"`                 // 基数
-	Salary           float64 `yaml:"salary" json:"salary"`                       // 薪水
-	SubsidyAmount    float64 `yaml:"subsidy_amount" json:"subsidy_amount"`       // 补贴
-	DeductibleAmount float64 `yaml:"deductible_amount" json:"deductible_amount"` // 抵扣金额
+	Threshold        Money `yaml:"threshold" json:"threshold"`                 // 基数
+	Salary           Money `yaml:"salary" json:"salary"`                       // 薪水
+	SubsidyAmount    Money `yaml:"subsidy_amount" json:"subsidy_amount"`       // 补贴
+	DeductibleAmount Money `yaml:"deductible_amount" json:"deductible_amount"` // 抵扣金额
+	ExtraAmount      Money `yaml:"extra_amount" json:"extra_amount"`           // 额外工资，如第13个月工资，计入当月收入，不计入缴费基数
 
 	AccumulationFundRate float64 `yaml:"accumulation_fund_rate" json:"accumulation_fund_rate"`
+	AccumulationFundBase Money   `yaml:"accumulation_fund_base" json:"accumulation_fund_base"`
+
+	// 未申报的缴费基数按 BaseSource 推导，再按各险种的上下限调整
+	BaseSource  BaseSource `yaml:"base_source" json:"base_source"`
+	AverageWage Money      `yaml:"average_wage" json:"average_wage"` // 上年度月平均工资
+
+	EndowmentBase        Money `yaml:"endowment_base" json:"endowment_base"`
+	MedicalBase          Money `yaml:"medical_base" json:"medical_base"`
+	UnemploymentBase     Money `yaml:"unemployment_base" json:"unemployment_base"`
+	EmploymentInjuryBase Money `yaml:"employment_injury_base" json:"employment_injury_base"`
+	BirthBase            Money `yaml:"birth_base" json:"birth_base"`
+	SeriousMedicalBase   Money `yaml:"serious_medical_base" json:"serious_medical_base"`
+
+	// 按险种的 base_key 申报的缴费基数，优先于上面的各项基数
+	Bases map[string]Money `yaml:"bases" json:"bases"`
+}
 
-	EndowmentBase        float64 `yaml:"endowment_base" json:"endowment_base"`
-	MedicalBase          float64 `yaml:"medical_base" json:"medical_base"`
-	UnemploymentBase     float64 `yaml:"unemployment_base" json:"unemployment_base"`
-	EmploymentInjuryBase float64 `yaml:"employment_injury_base" json:"employment_injury_base"`
-	BirthBase            float64 `yaml:"birth_base" json:"birth_base"`
-	SeriousMedicalBase   float64 `yaml:"serious_medical_base" json:"serious_medical_base"`
+// DeclaredBase 险种申报的缴费基数，未申报时为 0
+func (p *SalaryBase) DeclaredBase(baseKey string) Money {
+	if base, ok := p.Bases[baseKey]; ok {
+		return base
+	}
+	switch baseKey {
+	case "endowment":
+		return p.EndowmentBase
+	case "medical":
+		return p.MedicalBase
+	case "unemployment":
+		return p.UnemploymentBase
+	case "employment_injury":
+		return p.EmploymentInjuryBase
+	case "birth":
+		return p.BirthBase
+	case "serious_medical":
+		return p.SeriousMedicalBase
+	default:
+		return 0
+	}
 }
 
-// Decimal 处理浮点数精度
-func Decimal(value float64, pos int) float64 {
-	format := fmt.Sprintf("%%.%df", pos)
-	value, _ = strconv.ParseFloat(fmt.Sprintf(format, value), 64)
-	return value
+// DeriveBase 申报的基数大于 0 时直接使用，否则按基数来源推导
+func (p *SalaryBase) DeriveBase(declared Money) Money {
+	if declared > 0 {
+		return declared
+	}
+	switch p.BaseSource {
+	case BaseSourceAverageWage:
+		return p.AverageWage
+	default:
+		return p.Salary
+	}
 }
 
-// Decimal2 2位浮点精度
-func Decimal2(value float64) float64 {
-	return Decimal(value, 2)
+// Validate 校验薪水信息
+func (p *SalaryBase) Validate() error {
+	for _, item := range []struct {
+		name   string
+		amount Money
+	}{
+		{"起征线", p.Threshold}, {"月薪", p.Salary}, {"补贴", p.SubsidyAmount}, {"抵扣金额", p.DeductibleAmount},
+		{"额外工资", p.ExtraAmount}, {"公积金基数", p.AccumulationFundBase}, {"上年度月平均工资", p.AverageWage},
+		{"养老基数", p.EndowmentBase}, {"医疗基数", p.MedicalBase}, {"失业基数", p.UnemploymentBase},
+		{"工伤基数", p.EmploymentInjuryBase}, {"生育基数", p.BirthBase}, {"大病基数", p.SeriousMedicalBase},
+	} {
+		if item.amount < 0 {
+			return fmt.Errorf("%s不能小于0", item.name)
+		}
+	}
+	for key, base := range p.Bases {
+		if base < 0 {
+			return fmt.Errorf("%s 基数不能小于0", key)
+		}
+	}
+	if p.AccumulationFundRate < 0 || p.AccumulationFundRate > 100 {
+		return fmt.Errorf("公积金比例需在 0 和 100 之间")
+	}
+	switch p.BaseSource {
+	case "", BaseSourceSalary, BaseSourceAverageWage:
+	default:
+		return fmt.Errorf("缴费基数来源只能是 salary 或 average_wage: %s", p.BaseSource)
+	}
+	return nil
+}
+
+// Validate 校验个人信息
+func (p *PersonalInfo) Validate() error {
+	if p.Residence != ResidenceNonAgricultural && p.Residence != ResidenceAgricultural {
+		return fmt.Errorf("户口类型只能是 0 或 1: %d", p.Residence)
+	}
+	if p.Endowment != EndowmentWorkers && p.Endowment != EndowmentOffice {
+		return fmt.Errorf("养老类型只能是 0 或 1: %d", p.Endowment)
+	}
+	if p.SpecialDeductions != nil {
+		if err := p.SpecialDeductions.Validate(); err != nil {
+			return err
+		}
+	}
+	return p.SalaryBase.Validate()
 }
 
 // YearTaxBase 个税年情况
 type YearTaxBase struct {
 	YearTaxRates []YearTaxRate `yaml:"year_tax_rates" json:"year_tax_rates"`
+	// 按月换算后的综合所得税率表，用于全年一次性奖金单独计税
+	MonthTaxRates []YearTaxRate `yaml:"month_tax_rates" json:"month_tax_rates"`
+
+	ReconcileBase `yaml:"reconcile" json:"reconcile"`
+
+	// 各政策年度的专项附加扣除标准
+	SpecialDeductionStandards []SpecialDeductionStandard `yaml:"special_deduction_standards" json:"special_deduction_standards"`
+
+	// 税额的舍入方式，默认四舍五入到分
+	Rounding RoundingMode `yaml:"rounding" json:"rounding"`
+}
+
+// rounding 税额的舍入方式
+func (p *YearTaxBase) rounding() RoundingMode {
+	return p.Rounding.Or(RoundHalfUp)
+}
+
+// ReconcileBase 年度汇算参数
+type ReconcileBase struct {
+	BasicDeduction     Money   `yaml:"basic_deduction" json:"basic_deduction"`           // 年度基本减除费用
+	LaborExpenseRate   float64 `yaml:"labor_expense_rate" json:"labor_expense_rate"`     // 劳务报酬减除费用比例
+	AuthorExpenseRate  float64 `yaml:"author_expense_rate" json:"author_expense_rate"`   // 稿酬减除费用比例
+	AuthorDiscountRate float64 `yaml:"author_discount_rate" json:"author_discount_rate"` // 稿酬收入额减按比例
+	RoyaltyExpenseRate float64 `yaml:"royalty_expense_rate" json:"royalty_expense_rate"` // 特许权使用费减除费用比例
+	ExemptTaxDue       Money   `yaml:"exempt_tax_due" json:"exempt_tax_due"`             // 补税不超过该金额免于补税
+	ExemptIncome       Money   `yaml:"exempt_income" json:"exempt_income"`               // 年度综合所得收入不超过该金额免于补税
 }
 
 // YearTaxRate 年配置
 type YearTaxRate struct {
-	SalaryMin      float64 `yaml:"salary_min" json:"salary_min"`
-	SalaryMax      float64 `yaml:"salary_max" json:"salary_max"`
+	SalaryMin      Money   `yaml:"salary_min" json:"salary_min"`
+	SalaryMax      Money   `yaml:"salary_max" json:"salary_max"`
 	Rate           float64 `yaml:"rate" json:"rate"`
-	DeductedAmount float64 `yaml:"deducted_amount" json:"deducted_amount"`
+	DeductedAmount Money   `yaml:"deducted_amount" json:"deducted_amount"`
 }
@@ -0,0 +1,195 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// BonusMethod 全年一次性奖金计税方式
+type BonusMethod string
+
+// 全年一次性奖金计税方式
+const (
+	// 单独计税
+	BonusSeparate BonusMethod = "separate"
+	// 并入当年综合所得
+	BonusMerged BonusMethod = "merged"
+)
+
+// Bonus 全年一次性奖金
+type Bonus struct {
+	Amount Money       `yaml:"amount" json:"amount"` // 奖金金额
+	Month  int         `yaml:"month" json:"month"`   // 发放月份
+	Method BonusMethod `yaml:"method" json:"method"` // 计税方式，默认单独计税
+}
+
+// Validate 校验奖金信息
+func (p *Bonus) Validate() error {
+	if p.Amount < 0 {
+		return fmt.Errorf("奖金金额不能小于0")
+	}
+	if p.Amount > 0 && (p.Month < 1 || p.Month > 12) {
+		return fmt.Errorf("奖金发放月份需在 1 和 12 之间: %d", p.Month)
+	}
+	switch p.Method {
+	case "", BonusSeparate, BonusMerged:
+	default:
+		return fmt.Errorf("奖金计税方式只能是 separate 或 merged: %s", p.Method)
+	}
+	return nil
+}
+
+// BonusTaxes 奖金两种计税方式的对比结果
+type BonusTaxes struct {
+	Bonus Bonus `yaml:"bonus" json:"bonus"`
+
+	Separate *BonusPlan `yaml:"separate" json:"separate"`
+	Merged   *BonusPlan `yaml:"merged" json:"merged"`
+
+	// 全年个税更低的计税方式
+	Better BonusMethod `yaml:"better" json:"better"`
+}
+
+// BonusPlan 某一种计税方式下的全年情况
+type BonusPlan struct {
+	Method BonusMethod `yaml:"method" json:"method"`
+
+	// 因奖金增加的个税
+	BonusTaxation Money `yaml:"bonus_taxation" json:"bonus_taxation"`
+	// 全年个税
+	Taxation Money `yaml:"taxation" json:"taxation"`
+	// 全年税后收入
+	RestSalary Money `yaml:"rest_salary" json:"rest_salary"`
+
+	Taxes *MonthlyTaxes `yaml:"taxes" json:"taxes"`
+}
+
+// CalcBonusTax 按月度税率表计算单独计税的全年一次性奖金个税
+func (p *YearTaxBase) CalcBonusTax(amount Money) Money {
+	taxRate, ok := findTaxRate(p.MonthTaxRates, amount, 12)
+	if !ok {
+		return 0
+	}
+	return amount.MulRate(taxRate.Rate, p.rounding()) - taxRate.DeductedAmount
+}
+
+// CalcBonus 分别按单独计税和并入综合所得计算全年个税及税后收入
+func (p *TaxesHandler) CalcBonus(salaries *Salaries) (*BonusTaxes, error) {
+	salaries, err := salaries.Expand()
+	if err != nil {
+		return nil, err
+	}
+	if salaries.Bonus == nil || salaries.Bonus.Amount <= 0 {
+		return nil, fmt.Errorf("未配置全年一次性奖金")
+	}
+
+	base, err := p.calcWithBonusMethod(salaries, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BonusTaxes{Bonus: *salaries.Bonus}
+	for _, method := range []BonusMethod{BonusSeparate, BonusMerged} {
+		taxes, err := p.calcWithBonusMethod(salaries, method)
+		if err != nil {
+			return nil, err
+		}
+		plan := &BonusPlan{
+			Method:        method,
+			BonusTaxation: taxes.TotalTaxation() - base.TotalTaxation(),
+			Taxation:      taxes.TotalTaxation(),
+			RestSalary:    taxes.TotalRestSalary(),
+			Taxes:         taxes,
+		}
+		switch method {
+		case BonusSeparate:
+			result.Separate = plan
+		case BonusMerged:
+			result.Merged = plan
+		}
+	}
+
+	result.Better = BonusSeparate
+	if result.Merged.Taxation < result.Separate.Taxation {
+		result.Better = BonusMerged
+	}
+
+	return result, nil
+}
+
+// calcWithBonusMethod 指定奖金计税方式计算，method 为空时不计入奖金
+func (p *TaxesHandler) calcWithBonusMethod(salaries *Salaries, method BonusMethod) (*MonthlyTaxes, error) {
+	ss := *salaries
+	if method == "" {
+		ss.Bonus = nil
+	} else {
+		bonus := *salaries.Bonus
+		bonus.Method = method
+		ss.Bonus = &bonus
+	}
+	return p.Calc(&ss)
+}
+
+// BonusMethodName 计税方式名称
+func BonusMethodName(method BonusMethod) string {
+	switch method {
+	case BonusMerged:
+		return "并入综合所得"
+	default:
+		return "单独计税"
+	}
+}
+
+const (
+	printBonusInfor = "%s, 奖金个税: %10.2f, 全年个税: %10.2f, 全年税后收入: %12.2f"
+)
+
+// Print 打印信息
+func (p *BonusTaxes) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *BonusTaxes) Fprint(w io.Writer) {
+	fmt.Fprintln(w, fmt.Sprintf("全年一次性奖金: %.2f, 发放月份: %d月", p.Bonus.Amount, p.Bonus.Month))
+	for _, plan := range []*BonusPlan{p.Separate, p.Merged} {
+		fmt.Fprintln(w, fmt.Sprintf(printBonusInfor, BonusMethodName(plan.Method),
+			plan.BonusTaxation, plan.Taxation, plan.RestSalary))
+	}
+	fmt.Fprintln(w, fmt.Sprintf("\t建议: %s", BonusMethodName(p.Better)))
+}
+
+// Tables 转为表格
+func (p *BonusTaxes) Tables() []*Table {
+	t := &Table{
+		Title:   fmt.Sprintf("全年一次性奖金: %.2f, 发放月份: %d月", p.Bonus.Amount, p.Bonus.Month),
+		Headers: []string{"计税方式", "奖金个税", "全年个税", "全年税后收入", "建议"},
+	}
+	for _, plan := range []*BonusPlan{p.Separate, p.Merged} {
+		better := ""
+		if plan.Method == p.Better {
+			better = "是"
+		}
+		t.AddRow(BonusMethodName(plan.Method), plan.BonusTaxation.String(),
+			plan.Taxation.String(), plan.RestSalary.String(), better)
+	}
+	return []*Table{t}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// BonusDeadZone 年终奖陷阱区间，奖金落在 (Min, Max) 之间时多发的奖金还不够多交的个税
+type BonusDeadZone struct {
+	Min Money `yaml:"min" json:"min"`
+	Max Money `yaml:"max" json:"max"`
+}
+
+// Contains 奖金是否落在陷阱区间内
+func (p BonusDeadZone) Contains(amount Money) bool {
+	return amount > p.Min && amount < p.Max
+}
+
+// BonusDeadZones 根据月度税率表计算单独计税的年终奖陷阱区间
+func (p *YearTaxBase) BonusDeadZones() []BonusDeadZone {
+	var zones []BonusDeadZone
+	for i := 0; i+1 < len(p.MonthTaxRates); i++ {
+		lower, upper := p.MonthTaxRates[i], p.MonthTaxRates[i+1]
+		if lower.SalaryMax == 0 {
+			continue
+		}
+		min := lower.SalaryMax.Mul(12)
+		// 临界点的税后奖金
+		rest := min - p.CalcBonusTax(min)
+		// 进入下一档后，税后奖金回到临界点水平所需的奖金
+		max := (rest - upper.DeductedAmount).DivRate(100-upper.Rate, RoundHalfUp)
+		zones = append(zones, BonusDeadZone{Min: min, Max: max})
+	}
+	return zones
+}
+
+// InBonusDeadZone 奖金是否落在任一陷阱区间内
+func (p *YearTaxBase) InBonusDeadZone(amount Money) (BonusDeadZone, bool) {
+	for _, zone := range p.BonusDeadZones() {
+		if zone.Contains(amount) {
+			return zone, true
+		}
+	}
+	return BonusDeadZone{}, false
+}
+
+// maxBonusSearchPoints 奖金搜索的点数上限
+const maxBonusSearchPoints = 10000
+
+// ErrTooManySearchPoints 搜索步长过小，搜索点数超过上限
+var ErrTooManySearchPoints = errors.New("搜索点数过多")
+
+// BonusCandidate 一种奖金与月薪的拆分方案
+type BonusCandidate struct {
+	Bonus  Money       `yaml:"bonus" json:"bonus"`
+	Method BonusMethod `yaml:"method" json:"method"`
+	// 每月月薪的调整金额，负数表示从月薪转入奖金；平摊的舍入差额计入最后一个月
+	SalaryDelta Money `yaml:"salary_delta" json:"salary_delta"`
+
+	Taxation   Money `yaml:"taxation" json:"taxation"`
+	RestSalary Money `yaml:"rest_salary" json:"rest_salary"`
+
+	InDeadZone bool `yaml:"in_dead_zone" json:"in_dead_zone"`
+}
+
+// BonusOptimization 奖金拆分的优化结果
+type BonusOptimization struct {
+	// 全年税前总包：月薪合计与奖金之和
+	Total Money `yaml:"total" json:"total"`
+	Month int   `yaml:"month" json:"month"`
+
+	Current *BonusCandidate `yaml:"current" json:"current"`
+	Best    *BonusCandidate `yaml:"best" json:"best"`
+
+	DeadZones  []BonusDeadZone   `yaml:"dead_zones" json:"dead_zones"`
+	Candidates []*BonusCandidate `yaml:"candidates" json:"candidates"`
+}
+
+// OptimizeBonus 在总包不变的前提下，搜索月薪与奖金的拆分以及计税方式，使全年个税最低
+// ctx 取消或超时后停止搜索
+func (p *TaxesHandler) OptimizeBonus(ctx context.Context, salaries *Salaries, step Money) (*BonusOptimization, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("搜索步长需大于0")
+	}
+
+	// 按展开和补足后的每个月调整月薪
+	salaries, err := salaries.fixedMonths()
+	if err != nil {
+		return nil, err
+	}
+
+	withoutBonus := *salaries
+	withoutBonus.Bonus = nil
+	base, err := p.Calc(&withoutBonus)
+	if err != nil {
+		return nil, err
+	}
+	months := len(base.Taxes)
+	if months == 0 {
+		return nil, fmt.Errorf("未配置月工资")
+	}
+
+	// 未配置奖金时，默认在最后一个月发放
+	currentBonus := Bonus{Month: base.Taxes[months-1].Month, Method: BonusSeparate}
+	if salaries.Bonus != nil {
+		currentBonus = *salaries.Bonus
+		if currentBonus.Method == "" {
+			currentBonus.Method = BonusSeparate
+		}
+	}
+
+	// 奖金税率表使用发放月份适用的个税政策
+	h, err := p.Handler(salaries.PersonalInfo.Jurisdiction, salaries.yearMonth(currentBonus.Month))
+	if err != nil {
+		return nil, err
+	}
+
+	opt := &BonusOptimization{
+		Month:     currentBonus.Month,
+		DeadZones: h.BonusDeadZones(),
+	}
+	var totalSalaries Money
+	for _, t := range base.Taxes {
+		totalSalaries += t.Salary + t.ExtraAmount
+	}
+	opt.Total = totalSalaries + currentBonus.Amount
+	if opt.Total/step > maxBonusSearchPoints {
+		return nil, fmt.Errorf("%w: 奖金搜索点数不能超过 %d，请增大搜索步长", ErrTooManySearchPoints, maxBonusSearchPoints)
+	}
+
+	opt.Current, err = p.calcBonusCandidate(h, salaries, currentBonus, currentBonus.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, amount := range h.bonusSearchPoints(opt.Total, step) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for _, method := range []BonusMethod{BonusSeparate, BonusMerged} {
+			bonus := currentBonus
+			bonus.Method = method
+			candidate, err := p.calcBonusCandidate(h, salaries, bonus, amount)
+			if err != nil {
+				// 月薪过低导致公积金等无法计算的方案直接跳过
+				continue
+			}
+			opt.Candidates = append(opt.Candidates, candidate)
+		}
+	}
+
+	sort.SliceStable(opt.Candidates, func(i, j int) bool {
+		if opt.Candidates[i].Taxation != opt.Candidates[j].Taxation {
+			return opt.Candidates[i].Taxation < opt.Candidates[j].Taxation
+		}
+		return opt.Candidates[i].Bonus < opt.Candidates[j].Bonus
+	})
+
+	opt.Best = opt.Current
+	if len(opt.Candidates) > 0 && opt.Candidates[0].Taxation < opt.Current.Taxation {
+		opt.Best = opt.Candidates[0]
+	}
+
+	return opt, nil
+}
+
+// bonusSearchPoints 按步长生成奖金候选值，并补充各税率档临界点
+func (p *TaxesHandler) bonusSearchPoints(total, step Money) []Money {
+	points := map[Money]bool{}
+	for amount := Money(0); amount <= total; amount += step {
+		points[amount] = true
+	}
+	for _, rate := range p.MonthTaxRates {
+		if rate.SalaryMax == 0 || rate.SalaryMax.Mul(12) > total {
+			continue
+		}
+		points[rate.SalaryMax.Mul(12)] = true
+	}
+
+	var amounts []Money
+	for amount := range points {
+		amounts = append(amounts, amount)
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i] < amounts[j] })
+	return amounts
+}
+
+// splitBonus 将奖金调整为 amount，与原奖金的差额平摊到每个月的月薪，舍入的差额计入最后一个月，使总包保持不变
+// salaries 需为 fixedMonths 的结果，返回调整后的配置及每月的调整金额
+func (p *Salaries) splitBonus(bonus Bonus, amount Money) (*Salaries, Money, error) {
+	months := int64(len(p.MonthlySalaries))
+	diff := bonus.Amount - amount
+	delta := diff.Div(months, RoundHalfUp)
+
+	ss := *p
+	bonus.Amount = amount
+	ss.Bonus = &bonus
+	if amount == 0 {
+		ss.Bonus = nil
+	}
+	if diff != 0 {
+		ss.MonthlySalaries = make([]MonthlySalary, len(p.MonthlySalaries))
+		for i, s := range p.MonthlySalaries {
+			if int64(i) == months-1 {
+				s.Salary += diff - delta.Mul(months-1)
+			} else {
+				s.Salary += delta
+			}
+			if s.Salary < 0 {
+				return nil, 0, fmt.Errorf("月薪不能小于0")
+			}
+			ss.MonthlySalaries[i] = s
+		}
+	}
+	return &ss, delta, nil
+}
+
+func (p *TaxesHandler) calcBonusCandidate(h *TaxesHandler,
+	salaries *Salaries, bonus Bonus, amount Money) (*BonusCandidate, error) {
+
+	ss, delta, err := salaries.splitBonus(bonus, amount)
+	if err != nil {
+		return nil, err
+	}
+	taxes, err := p.Calc(ss)
+	if err != nil {
+		return nil, err
+	}
+
+	_, inDeadZone := h.InBonusDeadZone(amount)
+
+	return &BonusCandidate{
+		Bonus:       amount,
+		Method:      bonus.Method,
+		SalaryDelta: delta,
+		Taxation:    taxes.TotalTaxation(),
+		RestSalary:  taxes.TotalRestSalary(),
+		InDeadZone:  inDeadZone && bonus.Method == BonusSeparate,
+	}, nil
+}
+
+const (
+	printBonusCandidate = "%s, 奖金: %10.2f(%s), 月薪调整: %10.2f, 全年个税: %10.2f, 全年税后收入: %12.2f"
+)
+
+// Print 打印信息
+func (p *BonusOptimization) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *BonusOptimization) Fprint(w io.Writer) {
+	fmt.Fprintln(w, fmt.Sprintf("全年税前总包: %.2f, 奖金发放月份: %d月", p.Total, p.Month))
+	p.Current.print(w, "当前方案")
+	p.Best.print(w, "最优方案")
+	fmt.Fprintln(w, fmt.Sprintf("\t可节省个税: %.2f", p.Current.Taxation-p.Best.Taxation))
+
+	fmt.Fprintln(w, "年终奖陷阱区间（单独计税时奖金应避开）:")
+	for _, zone := range p.DeadZones {
+		fmt.Fprintln(w, fmt.Sprintf("\t%.2f ~ %.2f", zone.Min, zone.Max))
+	}
+}
+
+// Tables 转为表格
+func (p *BonusOptimization) Tables() []*Table {
+	plans := &Table{
+		Title:   fmt.Sprintf("全年税前总包: %.2f, 奖金发放月份: %d月", p.Total, p.Month),
+		Headers: []string{"方案", "奖金", "计税方式", "月薪调整", "全年个税", "全年税后收入", "陷阱区间"},
+		Totals:  []string{"可节省个税", "", "", "", (p.Current.Taxation - p.Best.Taxation).String(), "", ""},
+	}
+	for _, c := range []struct {
+		name      string
+		candidate *BonusCandidate
+	}{{"当前方案", p.Current}, {"最优方案", p.Best}} {
+		inDeadZone := ""
+		if c.candidate.InDeadZone {
+			inDeadZone = "是"
+		}
+		plans.AddRow(c.name, c.candidate.Bonus.String(), BonusMethodName(c.candidate.Method),
+			c.candidate.SalaryDelta.String(), c.candidate.Taxation.String(),
+			c.candidate.RestSalary.String(), inDeadZone)
+	}
+
+	zones := &Table{
+		Title:   "年终奖陷阱区间",
+		Headers: []string{"最低", "最高"},
+	}
+	for _, zone := range p.DeadZones {
+		zones.AddRow(zone.Min.String(), zone.Max.String())
+	}
+	return []*Table{plans, zones}
+}
+
+func (p *BonusCandidate) print(w io.Writer, name string) {
+	line := fmt.Sprintf(printBonusCandidate, name, p.Bonus, BonusMethodName(p.Method),
+		p.SalaryDelta, p.Taxation, p.RestSalary)
+	if p.InDeadZone {
+		line += ", 注意: 奖金处于年终奖陷阱区间"
+	}
+	fmt.Fprintln(w, line)
+}
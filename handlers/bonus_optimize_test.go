@@ -0,0 +1,84 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"context"
+	"testing"
+)
+
+// totalPackage 全年税前总包：月薪、额外工资与奖金之和
+func totalPackage(salaries *Salaries) Money {
+	var total Money
+	for _, s := range salaries.MonthlySalaries {
+		total += s.Salary + s.ExtraAmount
+	}
+	if salaries.Bonus != nil {
+		total += salaries.Bonus.Amount
+	}
+	return total
+}
+
+func TestSplitBonusConservesPackage(t *testing.T) {
+	salaries := &Salaries{
+		Year: 2024,
+		For:  true,
+		MonthlySalaries: []MonthlySalary{
+			{SalaryBase: SalaryBase{Threshold: NewMoney(5000), Salary: NewMoney(20000)}},
+			{SalaryBase: SalaryBase{Threshold: NewMoney(5000), Salary: NewMoney(21000)}},
+			{SalaryBase: SalaryBase{Threshold: NewMoney(5000), Salary: NewMoney(22000), ExtraAmount: NewMoney(500)}},
+		},
+		Bonus: &Bonus{Amount: NewMoney(10000), Month: 12, Method: BonusSeparate},
+	}
+	fixed, err := salaries.fixedMonths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixed.MonthlySalaries) != 12 {
+		t.Fatalf("应补足到 12 个月, 实际 %d 个月", len(fixed.MonthlySalaries))
+	}
+	total := totalPackage(fixed)
+
+	// 差额不能被 12 整除时，舍入的差额计入最后一个月
+	for _, amount := range []Money{0, NewMoney(0.01), NewMoney(7000), NewMoney(36000.05), NewMoney(100000)} {
+		split, delta, err := fixed.splitBonus(*fixed.Bonus, amount)
+		if err != nil {
+			t.Fatalf("奖金 %s: %v", amount, err)
+		}
+		if got := totalPackage(split); got != total {
+			t.Errorf("奖金 %s: 总包 %s, 应为 %s", amount, got, total)
+		}
+		for i, s := range split.MonthlySalaries[:11] {
+			if got := s.Salary - fixed.MonthlySalaries[i].Salary; got != delta {
+				t.Errorf("奖金 %s: %d月调整 %s, 应为 %s", amount, i+1, got, delta)
+			}
+		}
+	}
+
+	h := &TaxesHandler{YearTaxBase: YearTaxBase{YearTaxRates: testYearTaxRates, MonthTaxRates: testMonthTaxRates}}
+	opt, err := h.OptimizeBonus(context.Background(), salaries, NewMoney(7000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt.Total != total {
+		t.Errorf("总包 %s, 应为 %s", opt.Total, total)
+	}
+	if opt.Best.Taxation > opt.Current.Taxation {
+		t.Errorf("最优方案个税 %s 高于当前方案 %s", opt.Best.Taxation, opt.Current.Taxation)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ymhhh/tax/charset"
+)
+
+// OfferComparison 多个 offer 的全年收入对比
+type OfferComparison struct {
+	Offers []*OfferSummary `yaml:"offers" json:"offers"`
+}
+
+// OfferSummary 一个 offer 的全年合计
+type OfferSummary struct {
+	Name         string `yaml:"name" json:"name"`
+	Jurisdiction string `yaml:"jurisdiction" json:"jurisdiction"`
+	Months       int    `yaml:"months" json:"months"`
+
+	// 税前收入：月薪、补贴、额外工资及全年一次性奖金
+	Gross Money `yaml:"gross" json:"gross"`
+
+	Insurances              Money `yaml:"insurances" json:"insurances"`                               // 个人社保
+	AccumulationFund        Money `yaml:"accumulation_fund" json:"accumulation_fund"`                 // 个人公积金
+	CompanyInsurances       Money `yaml:"company_insurances" json:"company_insurances"`               // 单位社保
+	CompanyAccumulationFund Money `yaml:"company_accumulation_fund" json:"company_accumulation_fund"` // 单位公积金
+	// 个人和单位缴纳的公积金合计，属于个人的延期财富
+	TotalAccumulationFund Money `yaml:"total_accumulation_fund" json:"total_accumulation_fund"`
+
+	Taxation Money `yaml:"taxation" json:"taxation"` // 个税，含奖金个税
+	NetCash  Money `yaml:"net_cash" json:"net_cash"` // 税后现金收入
+	// 税后现金加上公积金合计
+	TotalValue Money `yaml:"total_value" json:"total_value"`
+	// 单位的用工成本：税前收入加上单位社保和公积金
+	CompanyCost Money `yaml:"company_cost" json:"company_cost"`
+}
+
+// CompareOffers 分别计算每个 offer 的全年收入，names 与 offers 一一对应
+func (p *TaxesHandler) CompareOffers(names []string, offers []*Salaries) (*OfferComparison, error) {
+	if len(names) != len(offers) {
+		return nil, fmt.Errorf("offer 名称与配置的数量不一致")
+	}
+
+	comparison := &OfferComparison{}
+	for i, offer := range offers {
+		if err := offer.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", names[i], err)
+		}
+		taxes, err := p.Calc(offer)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", names[i], err)
+		}
+		comparison.Offers = append(comparison.Offers, summarizeOffer(names[i], offer.Jurisdiction, taxes))
+	}
+	return comparison, nil
+}
+
+func summarizeOffer(name, jurisdiction string, taxes *MonthlyTaxes) *OfferSummary {
+	totals := taxes.Totals()
+	s := &OfferSummary{
+		Name:                    name,
+		Jurisdiction:            jurisdiction,
+		Months:                  len(taxes.Taxes),
+		Gross:                   totals.Income,
+		Insurances:              totals.Insurances,
+		AccumulationFund:        totals.AccumulationFund,
+		CompanyInsurances:       totals.CompanyInsurances,
+		CompanyAccumulationFund: totals.CompanyAccumulationFund,
+		Taxation:                totals.Taxation,
+		NetCash:                 totals.RestSalary,
+		CompanyCost:             totals.CompanyCost,
+	}
+	s.TotalAccumulationFund = s.AccumulationFund + s.CompanyAccumulationFund
+	s.TotalValue = s.NetCash + s.TotalAccumulationFund
+	return s
+}
+
+// comparisonRow 对比的一行
+type comparisonRow struct {
+	name  string
+	value func(s *OfferSummary) Money
+}
+
+var comparisonRows = []comparisonRow{
+	{"税前收入", func(s *OfferSummary) Money { return s.Gross }},
+	{"个人社保", func(s *OfferSummary) Money { return s.Insurances }},
+	{"个人公积金", func(s *OfferSummary) Money { return s.AccumulationFund }},
+	{"单位社保", func(s *OfferSummary) Money { return s.CompanyInsurances }},
+	{"单位公积金", func(s *OfferSummary) Money { return s.CompanyAccumulationFund }},
+	{"公积金合计", func(s *OfferSummary) Money { return s.TotalAccumulationFund }},
+	{"个税", func(s *OfferSummary) Money { return s.Taxation }},
+	{"税后现金", func(s *OfferSummary) Money { return s.NetCash }},
+	{"税后+公积金", func(s *OfferSummary) Money { return s.TotalValue }},
+	{"用工成本", func(s *OfferSummary) Money { return s.CompanyCost }},
+}
+
+// Print 打印信息
+func (p *OfferComparison) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w，每个 offer 一列
+func (p *OfferComparison) Fprint(w io.Writer) {
+	const labelWidth = 12
+	line := charset.PadRight("", labelWidth)
+	for _, s := range p.Offers {
+		line += " " + charset.PadLeft(s.Name, 14)
+	}
+	fmt.Fprintln(w, line)
+
+	line = charset.PadRight("地区", labelWidth)
+	for _, s := range p.Offers {
+		line += " " + charset.PadLeft(s.Jurisdiction, 14)
+	}
+	fmt.Fprintln(w, line)
+
+	for _, row := range comparisonRows {
+		line = charset.PadRight(row.name, labelWidth)
+		for _, s := range p.Offers {
+			line += fmt.Sprintf(" %14.2f", row.value(s))
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	if best := p.Best(); best != nil && len(p.Offers) > 1 {
+		fmt.Fprintln(w, fmt.Sprintf("税后+公积金最高: %s", best.Name))
+	}
+}
+
+// Best 税后现金加公积金最高的 offer
+func (p *OfferComparison) Best() *OfferSummary {
+	var best *OfferSummary
+	for _, s := range p.Offers {
+		if best == nil || s.TotalValue > best.TotalValue {
+			best = s
+		}
+	}
+	return best
+}
+
+// Tables 转为表格，每个 offer 一列，表头带上地区，最后一行为与第一个 offer 相比的税后+公积金差额
+func (p *OfferComparison) Tables() []*Table {
+	t := &Table{
+		Title:   "offer 对比",
+		Headers: []string{"项目"},
+	}
+	for _, s := range p.Offers {
+		t.Headers = append(t.Headers, fmt.Sprintf("%s(%s)", s.Name, s.Jurisdiction))
+	}
+
+	for _, row := range comparisonRows {
+		cells := []string{row.name}
+		for _, s := range p.Offers {
+			cells = append(cells, row.value(s).String())
+		}
+		t.AddRow(cells...)
+	}
+
+	if len(p.Offers) > 1 {
+		t.Totals = []string{"税后+公积金差额"}
+		for _, s := range p.Offers {
+			t.Totals = append(t.Totals, (s.TotalValue - p.Offers[0].TotalValue).String())
+		}
+	}
+	return []*Table{t}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Step 计算过程中的一步，记录公式、输入、结果以及舍入方式，Steps 为其下的明细步骤
+type Step struct {
+	Name     string       `yaml:"name" json:"name"`
+	Formula  string       `yaml:"formula,omitempty" json:"formula,omitempty"`
+	Inputs   []StepInput  `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	Result   string       `yaml:"result,omitempty" json:"result,omitempty"`
+	Rounding RoundingMode `yaml:"rounding,omitempty" json:"rounding,omitempty"`
+	Note     string       `yaml:"note,omitempty" json:"note,omitempty"`
+
+	Steps []*Step `yaml:"steps,omitempty" json:"steps,omitempty"`
+}
+
+// StepInput 计算步骤的输入
+type StepInput struct {
+	Name  string `yaml:"name" json:"name"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// Explainer 可以输出计算过程的结果
+type Explainer interface {
+	Explain() *Step
+}
+
+// NewStep 生成计算步骤
+func NewStep(name, formula string) *Step {
+	return &Step{Name: name, Formula: formula}
+}
+
+// Input 追加输入
+func (p *Step) Input(name, value string) *Step {
+	p.Inputs = append(p.Inputs, StepInput{Name: name, Value: value})
+	return p
+}
+
+// Money 追加金额输入
+func (p *Step) Money(name string, amount Money) *Step {
+	return p.Input(name, amount.String())
+}
+
+// Rate 追加比例输入
+func (p *Step) Rate(name string, rate float64) *Step {
+	return p.Input(name, percent(rate))
+}
+
+// Add 追加明细步骤，忽略 nil
+func (p *Step) Add(steps ...*Step) *Step {
+	for _, step := range steps {
+		if step != nil {
+			p.Steps = append(p.Steps, step)
+		}
+	}
+	return p
+}
+
+// Set 设置结果
+func (p *Step) Set(result Money) *Step {
+	p.Result = result.String()
+	return p
+}
+
+// Round 设置舍入方式
+func (p *Step) Round(mode RoundingMode) *Step {
+	p.Rounding = mode
+	return p
+}
+
+// Explain 返回自身，便于直接输出
+func (p *Step) Explain() *Step {
+	return p
+}
+
+func percent(rate float64) string {
+	return fmt.Sprintf("%.2f%%", rate)
+}
+
+// Print 打印信息
+func (p *Step) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 以缩进的树形输出到 w
+func (p *Step) Fprint(w io.Writer) {
+	p.fprint(w, "")
+}
+
+func (p *Step) fprint(w io.Writer, indent string) {
+	fmt.Fprintln(w, indent+p.line())
+	if inputs := p.inputs(); inputs != "" {
+		fmt.Fprintln(w, indent+"    "+inputs)
+	}
+	if p.Note != "" {
+		fmt.Fprintln(w, indent+"    说明: "+p.Note)
+	}
+	for _, step := range p.Steps {
+		step.fprint(w, indent+"  ")
+	}
+}
+
+func (p *Step) line() string {
+	line := "- " + p.Name
+	if p.Formula != "" {
+		line += " = " + p.Formula
+	}
+	if p.Result != "" {
+		line += " = " + p.Result
+	}
+	if p.Rounding != "" {
+		line += fmt.Sprintf(" (舍入: %s)", p.Rounding)
+	}
+	return line
+}
+
+func (p *Step) inputs() string {
+	inputs := make([]string, len(p.Inputs))
+	for i, input := range p.Inputs {
+		inputs[i] = input.Name + ": " + input.Value
+	}
+	return strings.Join(inputs, ", ")
+}
+
+// Tables 转为表格，步骤名称按层级缩进
+func (p *Step) Tables() []*Table {
+	t := &Table{
+		Title:   p.Name,
+		Headers: []string{"步骤", "公式", "输入", "结果", "舍入", "说明"},
+	}
+	p.addRows(t, 0)
+	return []*Table{t}
+}
+
+func (p *Step) addRows(t *Table, depth int) {
+	t.AddRow(strings.Repeat("  ", depth)+p.Name, p.Formula, p.inputs(), p.Result, string(p.Rounding), p.Note)
+	for _, step := range p.Steps {
+		step.addRows(t, depth+1)
+	}
+}
+
+// explainBase 缴费基数的计算过程
+func explainBase(name string, info *SalaryBase, declared Money, result BaseResult, minBase, maxBase Money) *Step {
+	step := NewStep(name, "min(max(申报基数, 最低基数), 最高基数)").
+		Money("申报基数", result.Declared).Money("最低基数", minBase).Money("最高基数", maxBase).
+		Set(result.Actual)
+
+	var notes []string
+	if declared <= 0 {
+		switch info.BaseSource {
+		case BaseSourceAverageWage:
+			notes = append(notes, "未申报，按上年度月平均工资")
+		default:
+			notes = append(notes, "未申报，按月薪")
+		}
+	}
+	switch result.Adjustment {
+	case BaseRaisedToFloor:
+		notes = append(notes, "低于下限，按下限缴纳")
+	case BaseCappedAtCeiling:
+		notes = append(notes, "高于上限，按上限缴纳")
+	}
+	step.Note = strings.Join(notes, "；")
+	return step
+}
+
+// explainAmount 按比例计算缴费金额的过程
+func explainAmount(name string, base Money, rate float64, extra Money, rounding RoundingMode, result Money) *Step {
+	formula := "缴费基数 × 比例"
+	if extra != 0 {
+		formula += " + 固定金额"
+	}
+	step := NewStep(name, formula).Money("缴费基数", base).Rate("比例", rate)
+	if extra != 0 {
+		step.Money("固定金额", extra)
+	}
+	return step.Round(rounding).Set(result)
+}
+
+// explainTax 按税率表计算税额的过程，months 为档位上下限的倍数
+func explainTax(name, income string, amount Money, rate YearTaxRate, ok bool, months int64,
+	rounding RoundingMode, result Money) *Step {
+	step := NewStep(name, income+" × 税率 − 速算扣除数").Money(income, amount)
+	if !ok {
+		step.Note = "未达到第一档，不纳税"
+		return step.Set(result)
+	}
+
+	bracket := rate.SalaryMin.String() + " ~ "
+	if rate.SalaryMax == 0 {
+		bracket += "以上"
+	} else {
+		bracket += rate.SalaryMax.String()
+	}
+	step.Input("税率档", bracket).Rate("税率", rate.Rate).Money("速算扣除数", rate.DeductedAmount)
+	if months > 1 {
+		step.Note = fmt.Sprintf("按 %s ÷ %d = %s 查找税率档", income, months, amount.Div(months, RoundHalfUp).String())
+	}
+	return step.Round(rounding).Set(result)
+}
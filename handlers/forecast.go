@@ -0,0 +1,136 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BracketCrossing 累计应纳税所得额跨越税率级距
+type BracketCrossing struct {
+	// 新税率档的下限
+	Boundary Money   `yaml:"boundary" json:"boundary"`
+	From     float64 `yaml:"from" json:"from"`
+	To       float64 `yaml:"to" json:"to"`
+}
+
+func (p *BracketCrossing) String() string {
+	return fmt.Sprintf("累计应纳税所得额超过 %.2f, 税率 %g%% → %g%%", p.Boundary, p.From, p.To)
+}
+
+// BracketCrossings 同一个月跨越的税率级距，按级距从低到高排列
+type BracketCrossings []*BracketCrossing
+
+func (p BracketCrossings) String() string {
+	crossings := make([]string, 0, len(p))
+	for _, crossing := range p {
+		crossings = append(crossings, crossing.String())
+	}
+	return strings.Join(crossings, "；")
+}
+
+// NetPayForecast 补足月份的税后收入预测
+type NetPayForecast struct {
+	// 从该月起为补足的月份
+	From int `yaml:"from" json:"from"`
+	// 税后收入比上月下降的月份
+	Drops []*NetPayDrop `yaml:"drops" json:"drops"`
+}
+
+// NetPayDrop 税后收入比上月下降
+type NetPayDrop struct {
+	Year  int `yaml:"year" json:"year"`
+	Month int `yaml:"month" json:"month"`
+
+	Previous   Money `yaml:"previous" json:"previous"`
+	RestSalary Money `yaml:"rest_salary" json:"rest_salary"`
+	Drop       Money `yaml:"drop" json:"drop"`
+	// 此后的边际税率
+	TaxRate float64 `yaml:"tax_rate" json:"tax_rate"`
+	Reason  string  `yaml:"reason" json:"reason"`
+}
+
+// forecast 没有补足的月份时返回 nil
+func (p *MonthlyTaxes) forecast() *NetPayForecast {
+	var forecast *NetPayForecast
+	for i, t := range p.Taxes {
+		if !t.Projected {
+			continue
+		}
+		if forecast == nil {
+			forecast = &NetPayForecast{From: t.Month}
+		}
+		if i == 0 || t.RestSalary >= p.Taxes[i-1].RestSalary {
+			continue
+		}
+		prev := p.Taxes[i-1]
+		forecast.Drops = append(forecast.Drops, &NetPayDrop{
+			Year:       t.Year,
+			Month:      t.Month,
+			Previous:   prev.RestSalary,
+			RestSalary: t.RestSalary,
+			Drop:       prev.RestSalary - t.RestSalary,
+			TaxRate:    t.TaxRate,
+			Reason:     dropReason(prev, t),
+		})
+	}
+	return forecast
+}
+
+// dropReason 税后收入下降的原因
+func dropReason(prev, t *MonthlyTax) string {
+	switch {
+	case len(t.BracketCrossings) > 0:
+		return t.BracketCrossings.String()
+	case len(prev.BracketCrossings) > 0 && t.Taxation > prev.Taxation:
+		return fmt.Sprintf("上月跨越税率级距，本月起全部按 %g%% 预扣", t.TaxRate)
+	case t.Insurances+t.AccumulationFund > prev.Insurances+prev.AccumulationFund:
+		return "社保公积金缴纳增加"
+	case t.Taxation > prev.Taxation:
+		return "个税增加"
+	}
+	return "收入减少"
+}
+
+// fprint 输出预测
+func (p *NetPayForecast) fprint(w io.Writer) {
+	fmt.Fprintln(w, fmt.Sprintf("预测（%d月起按最后一条月工资补足）:", p.From))
+	if len(p.Drops) == 0 {
+		fmt.Fprintln(w, "\t税后收入不会下降")
+		return
+	}
+	for _, d := range p.Drops {
+		fmt.Fprintln(w, fmt.Sprintf("\t%2d月, 税后收入减少: %10.2f, 税后: %10.2f, 此后税率: %g%%, 原因: %s",
+			d.Month, d.Drop, d.RestSalary, d.TaxRate, d.Reason))
+	}
+}
+
+// table 转为表格
+func (p *NetPayForecast) table() *Table {
+	t := &Table{
+		Title:   fmt.Sprintf("税后收入下降预测（%d月起按最后一条月工资补足）", p.From),
+		Headers: []string{"月份", "上月税后", "本月税后", "减少", "此后税率", "原因"},
+	}
+	for _, d := range p.Drops {
+		t.AddRow(monthCell(d.Year, d.Month), d.Previous.String(), d.RestSalary.String(), d.Drop.String(),
+			rateCell(d.TaxRate), d.Reason)
+	}
+	return t
+}
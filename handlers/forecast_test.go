@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testYearTaxRates 2019 年起的综合所得年度税率表
+var testYearTaxRates = []YearTaxRate{
+	{SalaryMin: NewMoney(0), SalaryMax: NewMoney(36000), Rate: 3, DeductedAmount: NewMoney(0)},
+	{SalaryMin: NewMoney(36000), SalaryMax: NewMoney(144000), Rate: 10, DeductedAmount: NewMoney(2520)},
+	{SalaryMin: NewMoney(144000), SalaryMax: NewMoney(300000), Rate: 20, DeductedAmount: NewMoney(16920)},
+	{SalaryMin: NewMoney(300000), SalaryMax: NewMoney(420000), Rate: 25, DeductedAmount: NewMoney(31920)},
+	{SalaryMin: NewMoney(420000), SalaryMax: NewMoney(660000), Rate: 30, DeductedAmount: NewMoney(52920)},
+	{SalaryMin: NewMoney(660000), SalaryMax: NewMoney(960000), Rate: 35, DeductedAmount: NewMoney(85920)},
+	{SalaryMin: NewMoney(960000), SalaryMax: NewMoney(0), Rate: 45, DeductedAmount: NewMoney(181920)},
+}
+
+func TestBracketCrossings(t *testing.T) {
+	h := &TaxesHandler{YearTaxBase: YearTaxBase{YearTaxRates: testYearTaxRates}}
+
+	for _, c := range []struct {
+		name   string
+		extras []float64
+		want   []BracketCrossings
+	}{
+		{
+			name:   "每月跨越一档",
+			extras: []float64{0, 40000, 110000},
+			want: []BracketCrossings{
+				nil,
+				{{Boundary: NewMoney(36000), From: 3, To: 10}},
+				{{Boundary: NewMoney(144000), From: 10, To: 20}},
+			},
+		},
+		{
+			// 2月累计应纳税所得额 5000+5000+200000=210000，同时跨越 36000 和 144000
+			name:   "一个月跨越两档",
+			extras: []float64{0, 200000},
+			want: []BracketCrossings{
+				nil,
+				{
+					{Boundary: NewMoney(36000), From: 3, To: 10},
+					{Boundary: NewMoney(144000), From: 10, To: 20},
+				},
+			},
+		},
+		{
+			// 上月没有应纳税所得额时，从最低一档开始比较
+			name:   "首月跨越三档",
+			extras: []float64{300000},
+			want: []BracketCrossings{
+				{
+					{Boundary: NewMoney(36000), From: 3, To: 10},
+					{Boundary: NewMoney(144000), From: 10, To: 20},
+					{Boundary: NewMoney(300000), From: 20, To: 25},
+				},
+			},
+		},
+	} {
+		ctx := NewTaxContext()
+		for i, extra := range c.extras {
+			tax := &MonthlyTax{Month: i + 1}
+			tax.Threshold = NewMoney(5000)
+			tax.Salary = NewMoney(10000)
+			tax.ExtraAmount = NewMoney(extra)
+			h.getMonthTax(ctx, tax, i+1)
+
+			if !reflect.DeepEqual(tax.BracketCrossings, c.want[i]) {
+				t.Errorf("%s: %d月跨越 %q, 应为 %q", c.name, i+1, tax.BracketCrossings, c.want[i])
+			}
+		}
+	}
+}
@@ -19,53 +19,145 @@ package handlers
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/go-trellis/config"
 )
 
 // InsurancesHandler 社保对象
 type InsurancesHandler struct {
-	InsurancesBase `yaml:"insurances" json:"insurances"`
+	Insurances InsurancesBase `yaml:"insurances" json:"insurances"`
 }
 
-// InsurancesAmount 社保金额
-type InsurancesAmount struct {
-	// 基本养老
-	EndowmentAmount    float64 `yaml:"endowment_amount" json:"endowment_amount"`
-	MaxEndowmentAmount float64 `yaml:"max_endowment_amount" json:"max_endowment_amount"`
-	MinEndowmentAmount float64 `yaml:"min_endowment_amount" json:"min_endowment_amount"`
-	// 基本医保
-	MedicalAmount    float64 `yaml:"medical_amount" json:"medical_amount"`
-	MaxMedicalAmount float64 `yaml:"max_medical_amount" json:"max_medical_amount"`
-	MinMedicalAmount float64 `yaml:"min_medical_amount" json:"min_medical_amount"`
-	// 失业
-	UnemploymentAmount    float64 `yaml:"unemployment_amount" json:"unemployment_amount"`
-	MaxUnemploymentAmount float64 `yaml:"max_unemployment_amount" json:"max_unemployment_amount"`
-	MinUnemploymentAmount float64 `yaml:"min_unemployment_amount" json:"min_unemployment_amount"`
-	// 工伤
-	EmploymentInjuryAmount    float64 `yaml:"employment_injury_amount" json:"employment_injury_amount"`
-	MaxEmploymentInjuryAmount float64 `yaml:"max_employment_injury_amount" json:"max_employment_injury_amount"`
-	MinEmploymentInjuryAmount float64 `yaml:"min_employment_injury_amount" json:"min_employment_injury_amount"`
-	// 生育
-	BirthAmount    float64 `yaml:"birth_amount" json:"birth_amount"`
-	MaxBirthAmount float64 `yaml:"max_birth_amount" json:"max_birth_amount"`
-	MinBirthAmount float64 `yaml:"min_birth_amount" json:"min_birth_amount"`
-	// 大病医保
-	SeriousMedicalAmount    float64 `yaml:"serious_medical_amount" json:"serious_medical_amount"`
-	MaxSeriousMedicalAmount float64 `yaml:"max_serious_medical_amount" json:"max_serious_medical_amount"`
-	MinSeriousMedicalAmount float64 `yaml:"min_serious_medical_amount" json:"min_serious_medical_amount"`
+// InsurancesBase 社保险种列表，按顺序计算和打印
+type InsurancesBase []InsuranceItem
+
+// InsuranceItem 险种配置
+type InsuranceItem struct {
+	Key  string `yaml:"key" json:"key"`   // 险种标识
+	Name string `yaml:"name" json:"name"` // 显示名称
+	// 申报基数的分组，对应个人信息中 bases 的 key，为空时使用 Key
+	BaseKey string `yaml:"base_key" json:"base_key"`
+
+	Base `yaml:",inline" json:",inline"`
+
+	// 适用的户口类型，为空表示不限
+	Residences []ResidenceType `yaml:"residences" json:"residences"`
+	// 适用的养老类型，为空表示不限
+	Endowments []EndowmentType `yaml:"endowments" json:"endowments"`
+}
+
+// legacyInsuranceItems 按险种名称配置社保时，各险种对应的列表项
+var legacyInsuranceItems = []InsuranceItem{
+	{Key: "workers_endowment", Name: "职工基本养老", BaseKey: "endowment", Endowments: []EndowmentType{EndowmentWorkers}},
+	{Key: "office_endowment", Name: "机关基本养老", BaseKey: "endowment", Endowments: []EndowmentType{EndowmentOffice}},
+	{Key: "medical", Name: "基本医疗", BaseKey: "medical"},
+	{Key: "non_agricultural_unemployment", Name: "非农失业", BaseKey: "unemployment",
+		Residences: []ResidenceType{ResidenceNonAgricultural}},
+	{Key: "agricultural_unemployment", Name: "农业失业", BaseKey: "unemployment",
+		Residences: []ResidenceType{ResidenceAgricultural}},
+	{Key: "employment_injury", Name: "工伤保险", BaseKey: "employment_injury"},
+	{Key: "birth", Name: "生育保险", BaseKey: "birth"},
+	{Key: "serious_medical", Name: "大病医疗", BaseKey: "serious_medical"},
+}
+
+// UnmarshalYAML 支持险种列表，也兼容按险种名称配置的旧格式
+func (p *InsurancesBase) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items []InsuranceItem
+	if err := unmarshal(&items); err == nil {
+		*p = items
+		return nil
+	}
+
+	var bases map[string]Base
+	if err := unmarshal(&bases); err != nil {
+		return err
+	}
+	*p = nil
+	for _, item := range legacyInsuranceItems {
+		base, ok := bases[item.Key]
+		if !ok {
+			continue
+		}
+		item.Base = base
+		*p = append(*p, item)
+	}
+	return nil
+}
+
+// AppliesTo 险种是否适用于该人员
+func (p *InsuranceItem) AppliesTo(info *PersonalInfo) bool {
+	if len(p.Residences) > 0 && !containsResidence(p.Residences, info.Residence) {
+		return false
+	}
+	if len(p.Endowments) > 0 && !containsEndowment(p.Endowments, info.Endowment) {
+		return false
+	}
+	return true
+}
+
+func containsResidence(residences []ResidenceType, residence ResidenceType) bool {
+	for _, r := range residences {
+		if r == residence {
+			return true
+		}
+	}
+	return false
+}
+
+func containsEndowment(endowments []EndowmentType, endowment EndowmentType) bool {
+	for _, e := range endowments {
+		if e == endowment {
+			return true
+		}
+	}
+	return false
+}
+
+// baseKey 申报基数的分组
+func (p *InsuranceItem) baseKey() string {
+	if p.BaseKey == "" {
+		return p.Key
+	}
+	return p.BaseKey
+}
+
+// InsuranceAmount 单个险种的缴纳金额
+type InsuranceAmount struct {
+	Item       InsuranceItem `yaml:"item" json:"item"`
+	BaseResult BaseResult    `yaml:"base_result" json:"base_result"`
+
+	Company    Money `yaml:"company" json:"company"`
+	MinCompany Money `yaml:"min_company" json:"min_company"`
+	MaxCompany Money `yaml:"max_company" json:"max_company"`
+	Private    Money `yaml:"private" json:"private"`
+	MinPrivate Money `yaml:"min_private" json:"min_private"`
+	MaxPrivate Money `yaml:"max_private" json:"max_private"`
 }
 
 // CalcInsurancesAmount 结果对象
 type CalcInsurancesAmount struct {
-	InsurancesBase `yaml:"insurances" json:"insurances"`
-	PersonalInfo   `yaml:"persion_info" json:"persion_info"`
+	PersonalInfo `yaml:"persion_info" json:"persion_info"`
+
+	// 适用于该人员的各险种缴纳金额
+	Items []*InsuranceAmount `yaml:"items" json:"items"`
 
-	Company InsurancesAmount `yaml:"company_insurances" json:"company_insurances"`
-	Private InsurancesAmount `yaml:"private_insurances" json:"private_insurances"`
+	CompanyTotalAmount Money `yaml:"company_total_amount" json:"company_total_amount"`
+	PrivateTotalAmount Money `yaml:"private_total_amount" json:"private_total_amount"`
 
-	CompanyTotalAmount float64 `yaml:"company_total_amount" json:"company_total_amount"`
-	PrivateTotalAmount float64 `yaml:"private_total_amount" json:"private_total_amount"`
+	// 计算过程
+	Explanation *Step `yaml:"-" json:"-"`
+}
+
+// Item 按险种标识查找缴纳金额
+func (p *CalcInsurancesAmount) Item(key string) (*InsuranceAmount, bool) {
+	for _, item := range p.Items {
+		if item.Item.Key == key {
+			return item, true
+		}
+	}
+	return nil, false
 }
 
 // NewInsurancesHandler 生成社保对象
@@ -84,193 +176,85 @@ const (
 
 // Print 打印信息
 func (p *CalcInsurancesAmount) Print() {
-	switch p.PersonalInfo.Endowment {
-	case EndowmentOffice:
-		fmt.Println(fmt.Sprintf(printInsuranceInfor, "基本养老",
-			p.InsurancesBase.OfficeEndowment.MinBase, p.InsurancesBase.OfficeEndowment.MaxBase,
-			p.InsurancesBase.OfficeEndowment.CompanyRate, p.InsurancesBase.OfficeEndowment.PrivateRate,
-			p.Company.MinEndowmentAmount, p.Company.MaxEndowmentAmount,
-			p.Private.MinEndowmentAmount, p.Private.MaxEndowmentAmount,
-			p.PersonalInfo.EndowmentBase, p.Company.EndowmentAmount, p.Private.EndowmentAmount,
-		))
-	default:
-		fmt.Println(fmt.Sprintf(printInsuranceInfor, "基本养老",
-			p.InsurancesBase.WorkersEndowment.MinBase, p.InsurancesBase.WorkersEndowment.MaxBase,
-			p.InsurancesBase.WorkersEndowment.CompanyRate, p.InsurancesBase.WorkersEndowment.PrivateRate,
-			p.Company.MinEndowmentAmount, p.Company.MaxEndowmentAmount,
-			p.Private.MinEndowmentAmount, p.Private.MaxEndowmentAmount,
-			p.PersonalInfo.EndowmentBase, p.Company.EndowmentAmount, p.Private.EndowmentAmount,
-		))
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *CalcInsurancesAmount) Fprint(w io.Writer) {
+	for _, item := range p.Items {
+		line := fmt.Sprintf(printInsuranceInfor, item.Item.Name,
+			item.Item.MinBase, item.Item.MaxBase,
+			item.Item.CompanyRate, item.Item.PrivateRate,
+			item.MinCompany, item.MaxCompany,
+			item.MinPrivate, item.MaxPrivate,
+			item.BaseResult.Actual, item.Company, item.Private,
+		) + item.BaseResult.Note()
+		fmt.Fprintln(w, line)
 	}
 
-	fmt.Println(fmt.Sprintf(printInsuranceInfor, "基本医疗",
-		p.InsurancesBase.Medical.MinBase, p.InsurancesBase.Medical.MaxBase,
-		p.InsurancesBase.Medical.CompanyRate, p.InsurancesBase.Medical.PrivateRate,
-		p.Company.MinMedicalAmount, p.Company.MaxMedicalAmount,
-		p.Private.MinMedicalAmount, p.Private.MaxMedicalAmount,
-		p.PersonalInfo.MedicalBase, p.Company.MedicalAmount, p.Private.MedicalAmount,
-	))
-
-	switch p.PersonalInfo.Residence {
-	case ResidenceNonAgricultural:
-		fmt.Println(fmt.Sprintf(printInsuranceInfor, "失业保险",
-			p.InsurancesBase.NonAgriculturalUnemployment.MinBase, p.InsurancesBase.NonAgriculturalUnemployment.MaxBase,
-			p.InsurancesBase.NonAgriculturalUnemployment.CompanyRate, p.InsurancesBase.NonAgriculturalUnemployment.PrivateRate,
-			p.Company.MinUnemploymentAmount, p.Company.MaxUnemploymentAmount,
-			p.Private.MinUnemploymentAmount, p.Private.MaxUnemploymentAmount,
-			p.PersonalInfo.UnemploymentBase, p.Company.UnemploymentAmount, p.Private.UnemploymentAmount,
-		))
-	default:
-		fmt.Println(fmt.Sprintf(printInsuranceInfor, "失业保险",
-			p.InsurancesBase.AgriculturalUnemployment.MinBase, p.InsurancesBase.AgriculturalUnemployment.MaxBase,
-			p.InsurancesBase.AgriculturalUnemployment.CompanyRate, p.InsurancesBase.AgriculturalUnemployment.PrivateRate,
-			p.Company.MinUnemploymentAmount, p.Company.MaxUnemploymentAmount,
-			p.Private.MinUnemploymentAmount, p.Private.MaxUnemploymentAmount,
-			p.PersonalInfo.UnemploymentBase, p.Company.UnemploymentAmount, p.Private.UnemploymentAmount,
-		))
+	fmt.Fprintln(w, fmt.Sprintf("\t单位总承担: %0.2f, 个人总承担: %0.2f", p.CompanyTotalAmount, p.PrivateTotalAmount))
+}
+
+// Explain 计算过程
+func (p *CalcInsurancesAmount) Explain() *Step {
+	return p.Explanation
+}
+
+// Tables 转为表格
+func (p *CalcInsurancesAmount) Tables() []*Table {
+	t := &Table{
+		Title:   "社会保险",
+		Headers: []string{"险种", "实际基数", "单位比例", "个人比例", "单位缴纳", "个人缴纳"},
+		Totals:  []string{"合计", "", "", "", p.CompanyTotalAmount.String(), p.PrivateTotalAmount.String()},
+	}
+	for _, item := range p.Items {
+		t.AddRow(item.Item.Name, item.BaseResult.Actual.String(),
+			rateCell(item.Item.CompanyRate), rateCell(item.Item.PrivateRate),
+			item.Company.String(), item.Private.String())
 	}
-	fmt.Println(fmt.Sprintf(printInsuranceInfor, "工伤保险",
-		p.InsurancesBase.EmploymentInjury.MinBase, p.InsurancesBase.EmploymentInjury.MaxBase,
-		p.InsurancesBase.EmploymentInjury.CompanyRate, p.InsurancesBase.EmploymentInjury.PrivateRate,
-		p.Company.MinEmploymentInjuryAmount, p.Company.MaxEmploymentInjuryAmount,
-		p.Private.MinEmploymentInjuryAmount, p.Private.MaxEmploymentInjuryAmount,
-		p.PersonalInfo.EmploymentInjuryBase, p.Company.EmploymentInjuryAmount, p.Private.EmploymentInjuryAmount,
-	))
-
-	fmt.Println(fmt.Sprintf(printInsuranceInfor, "生育保险",
-		p.InsurancesBase.Birth.MinBase, p.InsurancesBase.Birth.MaxBase,
-		p.InsurancesBase.Birth.CompanyRate, p.InsurancesBase.Birth.PrivateRate,
-		p.Company.MinBirthAmount, p.Company.MaxBirthAmount,
-		p.Private.MinBirthAmount, p.Private.MaxBirthAmount,
-		p.PersonalInfo.BirthBase, p.Company.BirthAmount, p.Private.BirthAmount,
-	))
-
-	fmt.Println(fmt.Sprintf(printInsuranceInfor, "大病医疗",
-		p.InsurancesBase.SeriousMedical.MinBase, p.InsurancesBase.SeriousMedical.MaxBase,
-		p.InsurancesBase.SeriousMedical.CompanyRate, p.InsurancesBase.SeriousMedical.PrivateRate,
-		p.Company.MinSeriousMedicalAmount, p.Company.MaxSeriousMedicalAmount,
-		p.Private.MinSeriousMedicalAmount, p.Private.MaxSeriousMedicalAmount,
-		p.PersonalInfo.SeriousMedicalBase, p.Company.SeriousMedicalAmount, p.Private.SeriousMedicalAmount,
-	))
-
-	fmt.Println(fmt.Sprintf("\t单位总承担: %0.2f, 个人总承担: %0.2f", p.CompanyTotalAmount, p.PrivateTotalAmount))
+	return []*Table{t}
 }
 
 // Calc 计算
 func (p *InsurancesHandler) Calc(info *PersonalInfo) (*CalcInsurancesAmount, error) {
 	calc := &CalcInsurancesAmount{
-		InsurancesBase: p.InsurancesBase,
-		PersonalInfo:   *info,
-	}
-	switch info.Endowment {
-	case EndowmentOffice:
-		calc.Company.EndowmentAmount = Decimal2(info.EndowmentBase * p.OfficeEndowment.CompanyRate / 100.0)
-		calc.Company.MinEndowmentAmount = Decimal2(p.OfficeEndowment.MinBase * p.OfficeEndowment.CompanyRate / 100.0)
-		calc.Company.MaxEndowmentAmount = Decimal2(p.OfficeEndowment.MaxBase * p.OfficeEndowment.CompanyRate / 100.0)
-
-		calc.Private.EndowmentAmount = Decimal2(info.EndowmentBase * p.OfficeEndowment.PrivateRate / 100.0)
-		calc.Private.MinEndowmentAmount = Decimal2(p.OfficeEndowment.MinBase * p.OfficeEndowment.PrivateRate / 100.0)
-		calc.Private.MaxEndowmentAmount = Decimal2(p.OfficeEndowment.MaxBase * p.OfficeEndowment.PrivateRate / 100.0)
-	default:
-		// WorkersEndowment
-		calc.Company.EndowmentAmount = Decimal2(info.EndowmentBase * p.WorkersEndowment.CompanyRate / 100.0)
-		calc.Company.MinEndowmentAmount = Decimal2(p.WorkersEndowment.MinBase * p.WorkersEndowment.CompanyRate / 100.0)
-		calc.Company.MaxEndowmentAmount = Decimal2(p.WorkersEndowment.MaxBase * p.WorkersEndowment.CompanyRate / 100.0)
-
-		calc.Private.EndowmentAmount = Decimal2(info.EndowmentBase * p.WorkersEndowment.PrivateRate / 100.0)
-		calc.Private.MinEndowmentAmount = Decimal2(p.WorkersEndowment.MinBase * p.WorkersEndowment.PrivateRate / 100.0)
-		calc.Private.MaxEndowmentAmount = Decimal2(p.WorkersEndowment.MaxBase * p.WorkersEndowment.PrivateRate / 100.0)
+		PersonalInfo: *info,
+		Explanation:  NewStep("社会保险", ""),
 	}
 
-	calc.CompanyTotalAmount += calc.Company.EndowmentAmount
-	calc.PrivateTotalAmount += calc.Private.EndowmentAmount
-
-	switch info.Residence {
-	case ResidenceNonAgricultural:
-		// NonAgriculturalUnemployment
-		calc.Company.UnemploymentAmount =
-			Decimal2(info.UnemploymentBase * p.NonAgriculturalUnemployment.CompanyRate / 100.0)
-		calc.Company.MinUnemploymentAmount =
-			Decimal2(p.NonAgriculturalUnemployment.MinBase * p.NonAgriculturalUnemployment.CompanyRate / 100.0)
-		calc.Company.MaxUnemploymentAmount =
-			Decimal2(p.NonAgriculturalUnemployment.MaxBase * p.NonAgriculturalUnemployment.CompanyRate / 100.0)
-
-		calc.Private.UnemploymentAmount =
-			Decimal2(info.UnemploymentBase * p.NonAgriculturalUnemployment.PrivateRate / 100.0)
-		calc.Private.MinUnemploymentAmount =
-			Decimal2(p.NonAgriculturalUnemployment.MinBase * p.NonAgriculturalUnemployment.PrivateRate / 100.0)
-		calc.Private.MaxUnemploymentAmount =
-			Decimal2(p.NonAgriculturalUnemployment.MaxBase * p.NonAgriculturalUnemployment.PrivateRate / 100.0)
-	default:
-		// AgriculturalUnemployment
-		calc.Company.UnemploymentAmount =
-			Decimal2(info.UnemploymentBase * p.AgriculturalUnemployment.CompanyRate / 100.0)
-		calc.Company.MinUnemploymentAmount =
-			Decimal2(p.AgriculturalUnemployment.MinBase * p.AgriculturalUnemployment.CompanyRate / 100.0)
-		calc.Company.MaxUnemploymentAmount =
-			Decimal2(p.AgriculturalUnemployment.MaxBase * p.AgriculturalUnemployment.CompanyRate / 100.0)
-
-		calc.Private.UnemploymentAmount =
-			Decimal2(info.UnemploymentBase * p.AgriculturalUnemployment.PrivateRate / 100.0)
-		calc.Private.MinUnemploymentAmount =
-			Decimal2(p.AgriculturalUnemployment.MinBase * p.AgriculturalUnemployment.PrivateRate / 100.0)
-		calc.Private.MaxUnemploymentAmount =
-			Decimal2(p.AgriculturalUnemployment.MaxBase * p.AgriculturalUnemployment.PrivateRate / 100.0)
+	for _, item := range p.Insurances {
+		if !item.AppliesTo(info) {
+			continue
+		}
+
+		amount := &InsuranceAmount{
+			Item:       item,
+			BaseResult: item.Clamp(info.DeriveBase(info.DeclaredBase(item.baseKey()))),
+		}
+
+		amount.Company = item.Amount(amount.BaseResult.Actual, item.CompanyRate) + item.CompanyExtraPayment
+		amount.MinCompany = item.Amount(item.MinBase, item.CompanyRate) + item.CompanyExtraPayment
+		amount.MaxCompany = item.Amount(item.MaxBase, item.CompanyRate) + item.CompanyExtraPayment
+
+		amount.Private = item.Amount(amount.BaseResult.Actual, item.PrivateRate) + item.ExtraPayment
+		amount.MinPrivate = item.Amount(item.MinBase, item.PrivateRate) + item.ExtraPayment
+		amount.MaxPrivate = item.Amount(item.MaxBase, item.PrivateRate) + item.ExtraPayment
+
+		calc.CompanyTotalAmount += amount.Company
+		calc.PrivateTotalAmount += amount.Private
+		calc.Items = append(calc.Items, amount)
+
+		rounding := item.Rounding.Or(RoundHalfUp)
+		calc.Explanation.Add(NewStep(item.Name, "").Add(
+			explainBase("缴费基数", &info.SalaryBase, info.DeclaredBase(item.baseKey()), amount.BaseResult,
+				item.MinBase, item.MaxBase),
+			explainAmount("单位缴纳", amount.BaseResult.Actual, item.CompanyRate, item.CompanyExtraPayment,
+				rounding, amount.Company),
+			explainAmount("个人缴纳", amount.BaseResult.Actual, item.PrivateRate, item.ExtraPayment,
+				rounding, amount.Private),
+		))
 	}
+	calc.Explanation.Result = fmt.Sprintf("单位 %s, 个人 %s", calc.CompanyTotalAmount, calc.PrivateTotalAmount)
 
-	calc.CompanyTotalAmount += calc.Company.UnemploymentAmount
-	calc.PrivateTotalAmount += calc.Private.UnemploymentAmount
-
-	calc.Company.EmploymentInjuryAmount =
-		Decimal2(info.EmploymentInjuryBase * p.EmploymentInjury.CompanyRate / 100.0)
-	calc.Company.MinEmploymentInjuryAmount =
-		Decimal2(p.EmploymentInjury.MinBase * p.EmploymentInjury.CompanyRate / 100.0)
-	calc.Company.MaxEmploymentInjuryAmount =
-		Decimal2(p.EmploymentInjury.MaxBase * p.EmploymentInjury.CompanyRate / 100.0)
-	calc.Private.EmploymentInjuryAmount =
-		Decimal2(info.EmploymentInjuryBase * p.EmploymentInjury.PrivateRate / 100.0)
-	calc.Private.MinEmploymentInjuryAmount =
-		Decimal2(p.EmploymentInjury.MinBase * p.EmploymentInjury.PrivateRate / 100.0)
-	calc.Private.MaxEmploymentInjuryAmount =
-		Decimal2(p.EmploymentInjury.MaxBase * p.EmploymentInjury.PrivateRate / 100.0)
-
-	calc.CompanyTotalAmount += calc.Company.EmploymentInjuryAmount
-	calc.PrivateTotalAmount += calc.Private.EmploymentInjuryAmount
-
-	calc.Company.BirthAmount = Decimal2(info.BirthBase * p.Birth.CompanyRate / 100.0)
-	calc.Company.MinBirthAmount = Decimal2(p.Birth.MinBase * p.Birth.CompanyRate / 100.0)
-	calc.Company.MaxBirthAmount = Decimal2(p.Birth.MaxBase * p.Birth.CompanyRate / 100.0)
-	calc.Private.BirthAmount = Decimal2(info.BirthBase * p.Birth.PrivateRate / 100.0)
-	calc.Private.MinBirthAmount = Decimal2(p.Birth.MinBase * p.Birth.PrivateRate / 100.0)
-	calc.Private.MaxBirthAmount = Decimal2(p.Birth.MaxBase * p.Birth.PrivateRate / 100.0)
-
-	calc.CompanyTotalAmount += calc.Company.BirthAmount
-	calc.PrivateTotalAmount += calc.Private.BirthAmount
-
-	calc.Company.MedicalAmount = Decimal2(info.MedicalBase * p.Medical.CompanyRate / 100.0)
-	calc.Company.MinMedicalAmount = Decimal2(p.Medical.MinBase * p.Medical.CompanyRate / 100.0)
-	calc.Company.MaxMedicalAmount = Decimal2(p.Medical.MaxBase * p.Medical.CompanyRate / 100.0)
-	calc.Private.MedicalAmount = Decimal2(info.MedicalBase * p.Medical.PrivateRate / 100.0)
-	calc.Private.MinMedicalAmount = Decimal2(p.Medical.MinBase * p.Medical.PrivateRate / 100.0)
-	calc.Private.MaxMedicalAmount = Decimal2(p.Medical.MaxBase * p.Medical.PrivateRate / 100.0)
-
-	calc.CompanyTotalAmount += calc.Company.MedicalAmount
-	calc.PrivateTotalAmount += calc.Private.MedicalAmount
-
-	calc.Company.SeriousMedicalAmount = Decimal2(info.SeriousMedicalBase * p.SeriousMedical.CompanyRate / 100.0)
-	calc.Company.MinSeriousMedicalAmount = Decimal2(p.SeriousMedical.MinBase * p.SeriousMedical.CompanyRate / 100.0)
-	calc.Company.MaxSeriousMedicalAmount = Decimal2(p.SeriousMedical.MaxBase * p.SeriousMedical.CompanyRate / 100.0)
-	calc.Private.SeriousMedicalAmount =
-		Decimal2(info.SeriousMedicalBase*p.SeriousMedical.PrivateRate/100.0 + p.SeriousMedical.ExtraPayment)
-	calc.Private.MinSeriousMedicalAmount =
-		Decimal2(p.SeriousMedical.MinBase*p.SeriousMedical.PrivateRate/100.0 + p.SeriousMedical.ExtraPayment)
-	calc.Private.MaxSeriousMedicalAmount =
-		Decimal2(p.SeriousMedical.MaxBase*p.SeriousMedical.PrivateRate/100.0 + p.SeriousMedical.ExtraPayment)
-
-	calc.CompanyTotalAmount += calc.Company.SeriousMedicalAmount
-	calc.PrivateTotalAmount += calc.Private.SeriousMedicalAmount
-
-	calc.CompanyTotalAmount = Decimal2(calc.CompanyTotalAmount)
-	calc.PrivateTotalAmount = Decimal2(calc.PrivateTotalAmount)
 	return calc, nil
 }
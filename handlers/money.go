@@ -0,0 +1,255 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Money 金额，以分为单位的定点数
+type Money int64
+
+// 金额单位
+const (
+	Cent Money = 1
+	Yuan Money = 100
+)
+
+// NewMoney 由元转为金额，四舍五入到分
+func NewMoney(yuan float64) Money {
+	return Money(math.Round(yuan * 100))
+}
+
+// ParseMoney 解析以元为单位的十进制金额，超过两位小数的部分四舍五入
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("金额格式错误: %s", s)
+	}
+	return ratToMoney(r.Mul(r, big.NewRat(100, 1)), RoundHalfUp), nil
+}
+
+// Yuan 转为以元为单位的浮点数，只用于展示或作图
+func (m Money) Yuan() float64 {
+	return float64(m) / 100
+}
+
+// String 以元为单位、保留两位小数
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// Format 支持 %f、%v、%s，精度小于 2 时按四舍五入到对应位数展示
+func (m Money) Format(f fmt.State, verb rune) {
+	s := m.String()
+	if prec, ok := f.Precision(); ok && prec < 2 {
+		unit := int64(math.Pow10(2 - prec))
+		s = (roundRat(big.NewRat(int64(m), unit), RoundHalfUp) * Money(unit)).String()
+		if prec == 0 {
+			s = s[:len(s)-3]
+		} else {
+			s = s[:len(s)-1]
+		}
+	}
+	if width, ok := f.Width(); ok && len(s) < width {
+		pad := strings.Repeat(" ", width-len(s))
+		if f.Flag('-') {
+			s += pad
+		} else {
+			s = pad + s
+		}
+	}
+	fmt.Fprint(f, s)
+}
+
+// MulRate 乘以百分比，如 rate 为 8 表示 8%，按 mode 舍入
+func (m Money) MulRate(rate float64, mode RoundingMode) Money {
+	r := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(m)), ratePPM(rate)), big.NewInt(1000000))
+	return roundRat(r, mode)
+}
+
+// DivRate 除以百分比，如 rate 为 90 表示除以 90%，按 mode 舍入
+func (m Money) DivRate(rate float64, mode RoundingMode) Money {
+	r := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(1000000)), ratePPM(rate))
+	return roundRat(r, mode)
+}
+
+// Mul 乘以整数
+func (m Money) Mul(n int64) Money {
+	return m * Money(n)
+}
+
+// Div 除以整数，按 mode 舍入
+func (m Money) Div(n int64, mode RoundingMode) Money {
+	return roundRat(big.NewRat(int64(m), n), mode)
+}
+
+// Round 按 mode 舍入，舍入到元的方式会把分舍去
+func (m Money) Round(mode RoundingMode) Money {
+	return roundRat(big.NewRat(int64(m), 1), mode)
+}
+
+// Max 取较大值
+func (m Money) Max(o Money) Money {
+	if m > o {
+		return m
+	}
+	return o
+}
+
+// Min 取较小值
+func (m Money) Min(o Money) Money {
+	if m < o {
+		return m
+	}
+	return o
+}
+
+// UnmarshalYAML 从 yaml 解析，配置中按元填写
+func (m *Money) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// MarshalYAML 转为 yaml，以元为单位，精确保留两位小数
+// 按字符串输出，避免大金额被写成 1e+06 这样的科学计数法
+func (m Money) MarshalYAML() (interface{}, error) {
+	return m.String(), nil
+}
+
+// UnmarshalJSON 从 json 解析，支持数字和字符串
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// MarshalJSON 转为 json，以元为单位，精确保留两位小数
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.RawMessage(m.String()), nil
+}
+
+// RoundingMode 舍入方式
+type RoundingMode string
+
+// 舍入方式
+const (
+	// 四舍五入到分（默认）
+	RoundHalfUp RoundingMode = "half_up"
+	// 四舍六入五成双到分
+	RoundHalfEven RoundingMode = "half_even"
+	// 截断到分
+	RoundTruncate RoundingMode = "truncate"
+	// 见分进元
+	RoundUpYuan RoundingMode = "up_yuan"
+	// 四舍五入到元
+	RoundHalfUpYuan RoundingMode = "half_up_yuan"
+)
+
+// unit 舍入的单位
+func (p RoundingMode) unit() int64 {
+	switch p {
+	case RoundUpYuan, RoundHalfUpYuan:
+		return int64(Yuan)
+	default:
+		return int64(Cent)
+	}
+}
+
+// Or mode 为空时使用 def
+func (p RoundingMode) Or(def RoundingMode) RoundingMode {
+	if p == "" {
+		return def
+	}
+	return p
+}
+
+// ratePPM 百分比转为百万分之一的整数，比例最多保留四位小数
+func ratePPM(rate float64) *big.Int {
+	return big.NewInt(int64(math.Round(rate * 10000)))
+}
+
+// roundRat 把以分为单位的有理数按 mode 舍入到对应的单位
+func roundRat(r *big.Rat, mode RoundingMode) Money {
+	unit := mode.unit()
+	if unit == 1 {
+		return ratToMoney(r, mode)
+	}
+	return ratToMoney(new(big.Rat).Quo(r, big.NewRat(unit, 1)), mode) * Money(unit)
+}
+
+// ratToMoney 把有理数按 mode 舍入为整数
+func ratToMoney(r *big.Rat, mode RoundingMode) Money {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return Money(q.Int64())
+	}
+
+	sign := int64(num.Sign())
+	twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+	cmp := twice.Cmp(den)
+
+	roundAway := false
+	switch mode {
+	case RoundTruncate:
+	case RoundUpYuan:
+		// 见分进元，向上取整
+		roundAway = sign > 0
+	case RoundHalfEven:
+		roundAway = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	default:
+		roundAway = cmp >= 0
+	}
+
+	if roundAway {
+		q.Add(q, big.NewInt(sign))
+	}
+	return Money(q.Int64())
+}
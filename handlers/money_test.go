@@ -0,0 +1,183 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseMoney(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want Money
+	}{
+		{"", 0},
+		{"0", 0},
+		{"1", Yuan},
+		{" 12.3 ", 1230},
+		{"0.01", Cent},
+		{"1000000", 100000000},
+		{"1e6", 100000000},
+		{"0.005", Cent},
+		{"0.0049", 0},
+		{"-0.005", -Cent},
+		{"-12.345", -1235},
+	} {
+		got, err := ParseMoney(c.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseMoney(%q) = %d, 应为 %d", c.in, got, c.want)
+		}
+	}
+
+	for _, in := range []string{"abc", "1,000", "1.2.3"} {
+		if _, err := ParseMoney(in); err == nil {
+			t.Errorf("ParseMoney(%q) 应返回错误", in)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	for _, c := range []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{Cent, "0.01"},
+		{-Cent, "-0.01"},
+		{123456, "1234.56"},
+		{100000000, "1000000.00"},
+	} {
+		if got := c.in.String(); got != c.want {
+			t.Errorf("Money(%d).String() = %s, 应为 %s", c.in, got, c.want)
+		}
+	}
+
+	if got := fmt.Sprintf("%10.2f|%-8.0f|%.1f", Money(123456), Money(150), Money(125)); got != "   1234.56|2       |1.3" {
+		t.Errorf("格式化结果 %q", got)
+	}
+}
+
+func TestMoneyRounding(t *testing.T) {
+	for _, c := range []struct {
+		m    Money
+		n    int64
+		mode RoundingMode
+		want Money
+	}{
+		{5, 2, RoundHalfUp, 3},
+		{5, 2, RoundHalfEven, 2},
+		{7, 2, RoundHalfEven, 4},
+		{5, 2, RoundTruncate, 2},
+		{-5, 2, RoundHalfUp, -3},
+		{-5, 2, RoundTruncate, -2},
+		{10001, 1, RoundUpYuan, 10100},
+		{10000, 1, RoundUpYuan, 10000},
+		{10049, 1, RoundHalfUpYuan, 10000},
+		{10050, 1, RoundHalfUpYuan, 10100},
+		{100000, 3, RoundHalfUp, 33333},
+	} {
+		if got := c.m.Div(c.n, c.mode); got != c.want {
+			t.Errorf("Money(%d).Div(%d, %s) = %d, 应为 %d", c.m, c.n, c.mode, got, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		m    Money
+		rate float64
+		mode RoundingMode
+		want Money
+	}{
+		{NewMoney(30000), 8, RoundHalfUp, NewMoney(2400)},
+		{NewMoney(12345.67), 0.5, RoundHalfUp, NewMoney(61.73)},
+		{NewMoney(12345.67), 0.5, RoundTruncate, NewMoney(61.72)},
+		{NewMoney(12345.67), 0.5, RoundUpYuan, NewMoney(62)},
+		{NewMoney(31884), 10.5, RoundHalfUp, NewMoney(3347.82)},
+	} {
+		if got := c.m.MulRate(c.rate, c.mode); got != c.want {
+			t.Errorf("%s.MulRate(%g, %s) = %s, 应为 %s", c.m, c.rate, c.mode, got, c.want)
+		}
+	}
+
+	if got := NewMoney(90).DivRate(90, RoundHalfUp); got != NewMoney(100) {
+		t.Errorf("DivRate = %s, 应为 100.00", got)
+	}
+}
+
+type moneyHolder struct {
+	Amount Money   `yaml:"amount" json:"amount"`
+	List   []Money `yaml:"list" json:"list"`
+}
+
+func TestMoneyJSON(t *testing.T) {
+	v := moneyHolder{Amount: 100000000, List: []Money{Cent, -123456, 0}}
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount":1000000.00,"list":[0.01,-1234.56,0.00]}`; string(data) != want {
+		t.Errorf("json = %s, 应为 %s", data, want)
+	}
+
+	var got moneyHolder
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(v) {
+		t.Errorf("json 往返后为 %v, 应为 %v", got, v)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount":"12.34","list":null}`), &got); err != nil || got.Amount != 1234 {
+		t.Errorf("解析字符串金额: %v, %s", err, got.Amount)
+	}
+}
+
+func TestMoneyYAML(t *testing.T) {
+	v := moneyHolder{Amount: 100000000, List: []Money{Cent, -123456, 0}}
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "amount: \"1000000.00\"\nlist:\n- \"0.01\"\n- \"-1234.56\"\n- \"0.00\"\n"
+	if string(data) != want {
+		t.Errorf("yaml = %q, 应为 %q", data, want)
+	}
+
+	var got moneyHolder
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(v) {
+		t.Errorf("yaml 往返后为 %v, 应为 %v", got, v)
+	}
+
+	// 配置文件中按元填写的数字
+	if err := yaml.Unmarshal([]byte("amount: 30000\nlist: [0.1, 1e6]\n"), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Amount != NewMoney(30000) || got.List[0] != 10 || got.List[1] != 100000000 {
+		t.Errorf("解析配置中的金额: %v", got)
+	}
+}
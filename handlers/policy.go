@@ -0,0 +1,439 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// YearMonth 年月，配置中写作 2019-07，零值表示不限
+type YearMonth struct {
+	Year  int
+	Month int
+}
+
+const (
+	yearMonthLayout = "2006-01"
+	dateLayout      = "2006-01-02"
+)
+
+// ParseYearMonth 解析 2019-07 格式的年月，也可以是 2019-07-15 格式的日期，取其所在月份
+func ParseYearMonth(s string) (YearMonth, error) {
+	if s == "" {
+		return YearMonth{}, nil
+	}
+	t, err := time.Parse(yearMonthLayout, s)
+	if err != nil {
+		if t, err = time.Parse(dateLayout, s); err != nil {
+			return YearMonth{}, fmt.Errorf("年月格式需为 2006-01: %s", s)
+		}
+	}
+	return YearMonth{Year: t.Year(), Month: int(t.Month())}, nil
+}
+
+// IsZero 是否为零值
+func (p YearMonth) IsZero() bool {
+	return p.Year == 0 && p.Month == 0
+}
+
+// Before 是否早于 o
+func (p YearMonth) Before(o YearMonth) bool {
+	return p.Year < o.Year || (p.Year == o.Year && p.Month < o.Month)
+}
+
+// AddMonths 加上 n 个月
+func (p YearMonth) AddMonths(n int) YearMonth {
+	months := p.Year*12 + p.Month - 1 + n
+	return YearMonth{Year: months / 12, Month: months%12 + 1}
+}
+
+func (p YearMonth) String() string {
+	if p.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d", p.Year, p.Month)
+}
+
+// UnmarshalYAML 从 yaml 解析
+func (p *YearMonth) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	ym, err := ParseYearMonth(s)
+	if err != nil {
+		return err
+	}
+	*p = ym
+	return nil
+}
+
+// MarshalYAML 转为 yaml
+func (p YearMonth) MarshalYAML() (interface{}, error) {
+	return p.String(), nil
+}
+
+// UnmarshalJSON 从 json 解析
+func (p *YearMonth) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	ym, err := ParseYearMonth(s)
+	if err != nil {
+		return err
+	}
+	*p = ym
+	return nil
+}
+
+// MarshalJSON 转为 json
+func (p YearMonth) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// Period 生效期间，EffectiveTo 为空表示至今有效
+type Period struct {
+	EffectiveFrom YearMonth `yaml:"effective_from" json:"effective_from"`
+	EffectiveTo   YearMonth `yaml:"effective_to" json:"effective_to"`
+}
+
+// Contains 是否在生效期间内
+func (p Period) Contains(month YearMonth) bool {
+	if !p.EffectiveFrom.IsZero() && month.Before(p.EffectiveFrom) {
+		return false
+	}
+	if !p.EffectiveTo.IsZero() && p.EffectiveTo.Before(month) {
+		return false
+	}
+	return true
+}
+
+func (p Period) String() string {
+	to := p.EffectiveTo.String()
+	if to == "" {
+		to = "至今"
+	}
+	return fmt.Sprintf("%s ~ %s", p.EffectiveFrom, to)
+}
+
+// ErrPolicyNotFound 地区或月份没有登记适用的政策
+var ErrPolicyNotFound = errors.New("没有适用的政策")
+
+// Policy 某一地区在生效期间内的五险一金参数
+type Policy struct {
+	Jurisdiction string `yaml:"jurisdiction" json:"jurisdiction"`
+	Name         string `yaml:"name" json:"name"`
+
+	Period `yaml:",inline" json:",inline"`
+
+	AccumulationFundHandler `yaml:",inline" json:",inline"`
+	InsurancesHandler       `yaml:",inline" json:",inline"`
+}
+
+// TaxPolicy 生效期间内的个税参数
+type TaxPolicy struct {
+	Period `yaml:",inline" json:",inline"`
+
+	YearTaxBase `yaml:",inline" json:",inline"`
+}
+
+// PolicyRegistry 按地区和生效期间登记的政策
+type PolicyRegistry struct {
+	// 未指定地区时使用的地区，为空时使用第一个政策的地区
+	DefaultJurisdiction string `yaml:"default_jurisdiction" json:"default_jurisdiction"`
+
+	Policies    []*Policy    `yaml:"policies" json:"policies"`
+	TaxPolicies []*TaxPolicy `yaml:"tax_policies" json:"tax_policies"`
+}
+
+// Policy 查找地区在某月适用的五险一金政策，month 为零值时返回该地区最新的政策
+func (p *PolicyRegistry) Policy(jurisdiction string, month YearMonth) (*Policy, error) {
+	if jurisdiction == "" {
+		jurisdiction = p.DefaultJurisdiction
+	}
+	if jurisdiction == "" && len(p.Policies) > 0 {
+		jurisdiction = p.Policies[0].Jurisdiction
+	}
+
+	var found *Policy
+	known := false
+	for _, policy := range p.Policies {
+		if policy.Jurisdiction != jurisdiction {
+			continue
+		}
+		known = true
+		if month.IsZero() {
+			if found == nil || found.EffectiveFrom.Before(policy.EffectiveFrom) {
+				found = policy
+			}
+			continue
+		}
+		if policy.Contains(month) {
+			return policy, nil
+		}
+	}
+	if found == nil {
+		if !known {
+			return nil, fmt.Errorf("%w: 未登记地区 %s 的五险一金政策", ErrPolicyNotFound, jurisdiction)
+		}
+		return nil, fmt.Errorf("%w: %s %s 没有五险一金政策", ErrPolicyNotFound, jurisdiction, month)
+	}
+	return found, nil
+}
+
+// TaxPolicy 查找某月适用的个税政策，month 为零值时返回最新的政策
+func (p *PolicyRegistry) TaxPolicy(month YearMonth) (*TaxPolicy, error) {
+	var found *TaxPolicy
+	for _, policy := range p.TaxPolicies {
+		if month.IsZero() {
+			if found == nil || found.EffectiveFrom.Before(policy.EffectiveFrom) {
+				found = policy
+			}
+			continue
+		}
+		if policy.Contains(month) {
+			return policy, nil
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s 没有个税政策", ErrPolicyNotFound, month)
+	}
+	return found, nil
+}
+
+// Jurisdictions 已登记的地区，按登记顺序
+func (p *PolicyRegistry) Jurisdictions() []string {
+	var jurisdictions []string
+	seen := map[string]bool{}
+	for _, policy := range p.Policies {
+		if seen[policy.Jurisdiction] {
+			continue
+		}
+		seen[policy.Jurisdiction] = true
+		jurisdictions = append(jurisdictions, policy.Jurisdiction)
+	}
+	return jurisdictions
+}
+
+// PoliciesOf 地区登记的全部政策
+func (p *PolicyRegistry) PoliciesOf(jurisdiction string) []*Policy {
+	var policies []*Policy
+	for _, policy := range p.Policies {
+		if policy.Jurisdiction == jurisdiction {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// Merge 合并另一份登记的政策
+func (p *PolicyRegistry) Merge(o *PolicyRegistry) {
+	if p.DefaultJurisdiction == "" {
+		p.DefaultJurisdiction = o.DefaultJurisdiction
+	}
+	p.Policies = append(p.Policies, o.Policies...)
+	p.TaxPolicies = append(p.TaxPolicies, o.TaxPolicies...)
+}
+
+const (
+	printPolicyBase = "\t%s, 基数: %.2f ~ %.2f, 单位比例: %.2f%%, 个人比例: %.2f%%, 单位固定金额: %.2f, 个人固定金额: %.2f"
+	printPolicyFund = "\t公积金, 基数: %.0f ~ %.0f, 比例: %.2f%% ~ %.2f%%"
+)
+
+// Print 打印信息
+func (p *Policy) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *Policy) Fprint(w io.Writer) {
+	fmt.Fprintln(w, fmt.Sprintf("%s(%s), 生效期间: %s", p.Name, p.Jurisdiction, p.Period))
+	for _, item := range p.Insurances {
+		fmt.Fprintln(w, fmt.Sprintf(printPolicyBase, item.Name, item.MinBase, item.MaxBase,
+			item.CompanyRate, item.PrivateRate, item.CompanyExtraPayment, item.ExtraPayment))
+	}
+	fmt.Fprintln(w, fmt.Sprintf(printPolicyFund, p.AccumulationFundBase.MinBase, p.AccumulationFundBase.MaxBase,
+		p.AccumulationFundBase.MinRate, p.AccumulationFundBase.MaxRate))
+}
+
+// Print 打印信息
+func (p *TaxPolicy) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *TaxPolicy) Fprint(w io.Writer) {
+	fmt.Fprintln(w, fmt.Sprintf("个税政策, 生效期间: %s, 年度基本减除费用: %.2f", p.Period, p.ReconcileBase.BasicDeduction))
+	for _, rate := range p.YearTaxRates {
+		max := fmt.Sprintf("%.0f", rate.SalaryMax)
+		if rate.SalaryMax == 0 {
+			max = "以上"
+		}
+		fmt.Fprintln(w, fmt.Sprintf("\t全年应纳税所得额: %.0f ~ %s, 税率: %.0f%%, 速算扣除数: %.0f",
+			rate.SalaryMin, max, rate.Rate, rate.DeductedAmount))
+	}
+}
+
+// Tables 转为表格
+func (p *Policy) Tables() []*Table {
+	t := &Table{
+		Title:   fmt.Sprintf("%s(%s), 生效期间: %s", p.Name, p.Jurisdiction, p.Period),
+		Headers: []string{"险种", "最低基数", "最高基数", "单位比例", "个人比例", "单位固定金额", "个人固定金额"},
+	}
+	for _, item := range p.Insurances {
+		t.AddRow(item.Name, item.MinBase.String(), item.MaxBase.String(),
+			rateCell(item.CompanyRate), rateCell(item.PrivateRate),
+			item.CompanyExtraPayment.String(), item.ExtraPayment.String())
+	}
+	fund := p.AccumulationFundBase
+	rate := rateCell(fund.MinRate) + "~" + rateCell(fund.MaxRate)
+	t.AddRow("公积金", fund.MinBase.String(), fund.MaxBase.String(), rate, rate, "", "")
+	return []*Table{t}
+}
+
+// Tables 转为表格
+func (p *TaxPolicy) Tables() []*Table {
+	t := &Table{
+		Title: fmt.Sprintf("个税政策, 生效期间: %s, 年度基本减除费用: %.2f",
+			p.Period, p.ReconcileBase.BasicDeduction),
+		Headers: []string{"全年应纳税所得额下限", "全年应纳税所得额上限", "税率", "速算扣除数"},
+	}
+	for _, rate := range p.YearTaxRates {
+		max := rate.SalaryMax.String()
+		if rate.SalaryMax == 0 {
+			max = ""
+		}
+		t.AddRow(rate.SalaryMin.String(), max, rateCell(rate.Rate), rate.DeductedAmount.String())
+	}
+	return []*Table{t}
+}
+
+// Print 打印信息
+func (p *PolicyRegistry) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 依次输出五险一金政策和个税政策到 w
+func (p *PolicyRegistry) Fprint(w io.Writer) {
+	for _, policy := range p.Policies {
+		policy.Fprint(w)
+	}
+	for _, policy := range p.TaxPolicies {
+		policy.Fprint(w)
+	}
+}
+
+// Tables 转为表格
+func (p *PolicyRegistry) Tables() []*Table {
+	var tables []*Table
+	for _, policy := range p.Policies {
+		tables = append(tables, policy.Tables()...)
+	}
+	for _, policy := range p.TaxPolicies {
+		tables = append(tables, policy.Tables()...)
+	}
+	return tables
+}
+
+// PolicySummary 政策概要
+type PolicySummary struct {
+	Jurisdiction string `yaml:"jurisdiction" json:"jurisdiction"`
+	Name         string `yaml:"name" json:"name"`
+	Period       Period `yaml:"period" json:"period"`
+}
+
+// PolicySummaries 政策概要列表
+type PolicySummaries []PolicySummary
+
+// Summaries 按地区列出五险一金政策，最后列出个税政策
+func (p *PolicyRegistry) Summaries() PolicySummaries {
+	var summaries PolicySummaries
+	for _, jurisdiction := range p.Jurisdictions() {
+		for _, policy := range p.PoliciesOf(jurisdiction) {
+			summaries = append(summaries, PolicySummary{
+				Jurisdiction: policy.Jurisdiction, Name: policy.Name, Period: policy.Period})
+		}
+	}
+	for _, policy := range p.TaxPolicies {
+		summaries = append(summaries, PolicySummary{Jurisdiction: "national", Name: "个税", Period: policy.Period})
+	}
+	return summaries
+}
+
+// Print 打印信息
+func (p PolicySummaries) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p PolicySummaries) Fprint(w io.Writer) {
+	for _, summary := range p {
+		fmt.Fprintln(w, fmt.Sprintf("%-10s %s, 生效期间: %s", summary.Jurisdiction, summary.Name, summary.Period))
+	}
+}
+
+// Tables 转为表格
+func (p PolicySummaries) Tables() []*Table {
+	t := &Table{
+		Title:   "内置政策",
+		Headers: []string{"地区", "名称", "生效期间"},
+	}
+	for _, summary := range p {
+		t.AddRow(summary.Jurisdiction, summary.Name, summary.Period.String())
+	}
+	return []*Table{t}
+}
+
+// Handler 选出地区在某月适用的政策，返回只包含这一套参数的 TaxesHandler
+// 没有登记政策时，沿用配置文件中直接设置的参数
+func (p *TaxesHandler) Handler(jurisdiction string, month YearMonth) (*TaxesHandler, error) {
+	if len(p.Policies) == 0 && len(p.TaxPolicies) == 0 {
+		return p, nil
+	}
+
+	h := &TaxesHandler{
+		AccumulationFundHandler: p.AccumulationFundHandler,
+		InsurancesHandler:       p.InsurancesHandler,
+		YearTaxBase:             p.YearTaxBase,
+	}
+
+	if len(p.Policies) > 0 {
+		policy, err := p.Policy(jurisdiction, month)
+		if err != nil {
+			return nil, err
+		}
+		h.AccumulationFundHandler = policy.AccumulationFundHandler
+		h.InsurancesHandler = policy.InsurancesHandler
+	}
+
+	if len(p.TaxPolicies) > 0 {
+		taxPolicy, err := p.TaxPolicy(month)
+		if err != nil {
+			return nil, err
+		}
+		h.YearTaxBase = taxPolicy.YearTaxBase
+	}
+
+	return h, nil
+}
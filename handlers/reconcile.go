@@ -0,0 +1,208 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ComprehensiveIncome 工资薪金以外的综合所得，以及年度汇算时补充的扣除
+type ComprehensiveIncome struct {
+	LaborRemuneration  Money `yaml:"labor_remuneration" json:"labor_remuneration"`   // 劳务报酬
+	AuthorRemuneration Money `yaml:"author_remuneration" json:"author_remuneration"` // 稿酬
+	Royalties          Money `yaml:"royalties" json:"royalties"`                     // 特许权使用费
+	WithheldTax        Money `yaml:"withheld_tax" json:"withheld_tax"`               // 以上所得已预扣预缴税额
+
+	SpecialAdditionalDeduction Money `yaml:"special_additional_deduction" json:"special_additional_deduction"` // 补充的专项附加扣除
+	OtherDeduction             Money `yaml:"other_deduction" json:"other_deduction"`                           // 依法确定的其他扣除
+}
+
+// Reconciliation 年度汇算结果
+type Reconciliation struct {
+	SalaryIncome  Money `yaml:"salary_income" json:"salary_income"`   // 工资薪金收入
+	LaborIncome   Money `yaml:"labor_income" json:"labor_income"`     // 劳务报酬收入额
+	AuthorIncome  Money `yaml:"author_income" json:"author_income"`   // 稿酬收入额
+	RoyaltyIncome Money `yaml:"royalty_income" json:"royalty_income"` // 特许权使用费收入额
+	Income        Money `yaml:"income" json:"income"`                 // 综合所得收入额合计
+	// 综合所得收入合计，劳务报酬、稿酬、特许权使用费按减除费用前的收入计算，用于判断免于补税
+	GrossIncome Money `yaml:"gross_income" json:"gross_income"`
+
+	BasicDeduction             Money `yaml:"basic_deduction" json:"basic_deduction"`
+	SpecialDeduction           Money `yaml:"special_deduction" json:"special_deduction"`
+	SpecialAdditionalDeduction Money `yaml:"special_additional_deduction" json:"special_additional_deduction"`
+	OtherDeduction             Money `yaml:"other_deduction" json:"other_deduction"`
+
+	TaxableIncome Money       `yaml:"taxable_income" json:"taxable_income"`
+	TaxRate       YearTaxRate `yaml:"tax_rate" json:"tax_rate"`
+	TaxPayable    Money       `yaml:"tax_payable" json:"tax_payable"`
+	WithheldTax   Money       `yaml:"withheld_tax" json:"withheld_tax"`
+
+	// 应退税额
+	Refund Money `yaml:"refund" json:"refund"`
+	// 应补税额
+	TaxDue Money `yaml:"tax_due" json:"tax_due"`
+	// 符合免于补税条件
+	Exempt bool `yaml:"exempt" json:"exempt"`
+}
+
+// Reconcile 年度汇算：按全年综合所得重新计算应纳税额，与已预扣预缴税额比较得出退税或补税
+func (p *TaxesHandler) Reconcile(salaries *Salaries) (*Reconciliation, error) {
+	salaries, err := salaries.Expand()
+	if err != nil {
+		return nil, err
+	}
+
+	taxes, err := p.Calc(salaries)
+	if err != nil {
+		return nil, err
+	}
+	if len(taxes.Taxes) == 0 {
+		return nil, fmt.Errorf("未配置月工资")
+	}
+	// 工资薪金使用全年各扣缴义务人的合计
+	annual := taxes.Taxes[len(taxes.Taxes)-1].Annual
+
+	// 汇算使用当年年末适用的个税政策
+	h, err := p.Handler(salaries.PersonalInfo.Jurisdiction, salaries.yearMonth(12))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reconciliation{
+		SalaryIncome:               annual.Income,
+		BasicDeduction:             h.ReconcileBase.BasicDeduction,
+		SpecialDeduction:           annual.SpecialDeduction,
+		SpecialAdditionalDeduction: annual.SpecialAdditionalDeduction,
+		WithheldTax:                annual.WithheldTax,
+	}
+
+	if deductions := salaries.PersonalInfo.SpecialDeductions; deductions != nil {
+		standard, err := h.SpecialDeductionStandard(salaries.year())
+		if err != nil {
+			return nil, err
+		}
+		annual, err := deductions.Annual(standard)
+		if err != nil {
+			return nil, err
+		}
+		r.SpecialAdditionalDeduction += annual
+	}
+
+	if other := salaries.OtherIncomes; other != nil {
+		rounding := h.rounding()
+		r.LaborIncome = other.LaborRemuneration.MulRate(100-h.ReconcileBase.LaborExpenseRate, rounding)
+		r.AuthorIncome = other.AuthorRemuneration.MulRate(
+			(100-h.ReconcileBase.AuthorExpenseRate)*h.ReconcileBase.AuthorDiscountRate/100.0, rounding)
+		r.RoyaltyIncome = other.Royalties.MulRate(100-h.ReconcileBase.RoyaltyExpenseRate, rounding)
+
+		r.SpecialAdditionalDeduction += other.SpecialAdditionalDeduction
+		r.OtherDeduction = other.OtherDeduction
+		r.WithheldTax += other.WithheldTax
+	}
+
+	r.Income = r.SalaryIncome + r.LaborIncome + r.AuthorIncome + r.RoyaltyIncome
+	r.GrossIncome = r.SalaryIncome
+	if other := salaries.OtherIncomes; other != nil {
+		r.GrossIncome += other.LaborRemuneration + other.AuthorRemuneration + other.Royalties
+	}
+	r.TaxableIncome = r.Income - r.BasicDeduction - r.SpecialDeduction -
+		r.SpecialAdditionalDeduction - r.OtherDeduction
+	if r.TaxableIncome < 0 {
+		r.TaxableIncome = 0
+	}
+	r.TaxRate, _ = h.FindYearTaxRate(r.TaxableIncome)
+	r.TaxPayable = h.CalcYearTax(r.TaxableIncome)
+
+	balance := r.TaxPayable - r.WithheldTax
+	switch {
+	case balance < 0:
+		r.Refund = -balance
+	case balance > 0:
+		r.TaxDue = balance
+		r.Exempt = balance <= h.ReconcileBase.ExemptTaxDue || r.GrossIncome <= h.ReconcileBase.ExemptIncome
+	}
+
+	return r, nil
+}
+
+const (
+	printReconcileIncome    = "综合所得收入额: %.2f, 工资薪金: %.2f, 劳务报酬: %.2f, 稿酬: %.2f, 特许权使用费: %.2f"
+	printReconcileDeduction = "减除费用: %.2f, 专项扣除: %.2f, 专项附加扣除: %.2f, 其他扣除: %.2f"
+	printReconcileTax       = "应纳税所得额: %.2f, 税率: %.2f%%, 速算扣除数: %.2f, 应纳税额: %.2f, 已预缴税额: %.2f"
+)
+
+// Print 打印信息
+func (p *Reconciliation) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *Reconciliation) Fprint(w io.Writer) {
+	fmt.Fprintln(w, fmt.Sprintf(printReconcileIncome,
+		p.Income, p.SalaryIncome, p.LaborIncome, p.AuthorIncome, p.RoyaltyIncome))
+	fmt.Fprintln(w, fmt.Sprintf(printReconcileDeduction,
+		p.BasicDeduction, p.SpecialDeduction, p.SpecialAdditionalDeduction, p.OtherDeduction))
+	fmt.Fprintln(w, fmt.Sprintf(printReconcileTax,
+		p.TaxableIncome, p.TaxRate.Rate, p.TaxRate.DeductedAmount, p.TaxPayable, p.WithheldTax))
+
+	switch {
+	case p.Refund > 0:
+		fmt.Fprintln(w, fmt.Sprintf("\t应退税额: %.2f", p.Refund))
+	case p.Exempt:
+		fmt.Fprintln(w, fmt.Sprintf("\t应补税额: %.2f, 符合免于补税条件，无需补税", p.TaxDue))
+	case p.TaxDue > 0:
+		fmt.Fprintln(w, fmt.Sprintf("\t应补税额: %.2f", p.TaxDue))
+	default:
+		fmt.Fprintln(w, "\t无需退税或补税")
+	}
+}
+
+// Tables 转为表格，合计行为应退或应补税额
+func (p *Reconciliation) Tables() []*Table {
+	t := &Table{
+		Title:   "年度汇算",
+		Headers: []string{"项目", "金额"},
+	}
+	t.AddRow("工资薪金", p.SalaryIncome.String())
+	t.AddRow("劳务报酬", p.LaborIncome.String())
+	t.AddRow("稿酬", p.AuthorIncome.String())
+	t.AddRow("特许权使用费", p.RoyaltyIncome.String())
+	t.AddRow("综合所得收入额", p.Income.String())
+	t.AddRow("综合所得收入合计(减除费用前)", p.GrossIncome.String())
+	t.AddRow("减除费用", p.BasicDeduction.String())
+	t.AddRow("专项扣除", p.SpecialDeduction.String())
+	t.AddRow("专项附加扣除", p.SpecialAdditionalDeduction.String())
+	t.AddRow("其他扣除", p.OtherDeduction.String())
+	t.AddRow("应纳税所得额", p.TaxableIncome.String())
+	t.AddRow("税率(%)", rateCell(p.TaxRate.Rate))
+	t.AddRow("速算扣除数", p.TaxRate.DeductedAmount.String())
+	t.AddRow("应纳税额", p.TaxPayable.String())
+	t.AddRow("已预缴税额", p.WithheldTax.String())
+
+	switch {
+	case p.Refund > 0:
+		t.Totals = []string{"应退税额", p.Refund.String()}
+	case p.Exempt:
+		t.Totals = []string{"应补税额(免于补税)", p.TaxDue.String()}
+	default:
+		t.Totals = []string{"应补税额", p.TaxDue.String()}
+	}
+	return []*Table{t}
+}
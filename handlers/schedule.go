@@ -0,0 +1,275 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schedule 月工资计划，从模板月工资开始按规则逐月展开
+type Schedule struct {
+	// 起始年月，默认为计算年度的1月
+	From YearMonth `yaml:"from" json:"from"`
+	// 结束年月，默认为起始年度的12月
+	To YearMonth `yaml:"to" json:"to"`
+
+	// 起始月的月工资
+	Template MonthlySalary `yaml:"template" json:"template"`
+
+	// 调整规则，同一月份的规则按配置顺序执行
+	Rules []ScheduleRule `yaml:"rules" json:"rules"`
+}
+
+// ScheduleRule 月工资调整规则
+// Salary、Raise、Base、FundRate 自该月起生效，Extra、Bonus 只在该月发放
+type ScheduleRule struct {
+	Month int `yaml:"month" json:"month"` // 生效月份
+
+	Salary   Money   `yaml:"salary" json:"salary"`       // 月薪调整为
+	Raise    float64 `yaml:"raise" json:"raise"`         // 月薪上涨百分比，负数为下调
+	Base     Money   `yaml:"base" json:"base"`           // 社保、公积金缴费基数调整为
+	FundRate float64 `yaml:"fund_rate" json:"fund_rate"` // 公积金比例调整为
+
+	Extra Money `yaml:"extra" json:"extra"` // 当月额外发放的工资，如第13个月工资
+	Bonus Money `yaml:"bonus" json:"bonus"` // 当月发放的全年一次性奖金
+}
+
+// Validate 校验规则
+func (p *ScheduleRule) Validate() error {
+	if p.Month < 1 || p.Month > 12 {
+		return fmt.Errorf("规则的月份需在 1 和 12 之间: %d", p.Month)
+	}
+	if p.Salary < 0 || p.Base < 0 || p.FundRate < 0 || p.Extra < 0 || p.Bonus < 0 {
+		return fmt.Errorf("%d 月的规则金额不能小于0", p.Month)
+	}
+	if p.Raise <= -100 {
+		return fmt.Errorf("%d 月的月薪下调比例需小于100%%", p.Month)
+	}
+	if p.Salary == 0 && p.Raise == 0 && p.Base == 0 && p.FundRate == 0 && p.Extra == 0 && p.Bonus == 0 {
+		return fmt.Errorf("%d 月的规则没有设置任何调整", p.Month)
+	}
+	return nil
+}
+
+// apply 执行自该月起生效的调整，返回调整说明
+func (p *ScheduleRule) apply(s *MonthlySalary) []string {
+	var changes []string
+	if p.Salary > 0 {
+		s.Salary = p.Salary
+		changes = append(changes, fmt.Sprintf("月薪调整为 %.2f", p.Salary))
+	}
+	if p.Raise != 0 {
+		s.Salary = s.Salary.MulRate(100+p.Raise, RoundHalfUp)
+		changes = append(changes, fmt.Sprintf("月薪调整 %+g%% 为 %.2f", p.Raise, s.Salary))
+	}
+	if p.Base > 0 {
+		s.AccumulationFundBase = p.Base
+		s.EndowmentBase = p.Base
+		s.MedicalBase = p.Base
+		s.UnemploymentBase = p.Base
+		s.EmploymentInjuryBase = p.Base
+		s.BirthBase = p.Base
+		s.SeriousMedicalBase = p.Base
+		// bases 中申报的基数优先，也需要调整；复制后修改，不影响之前的月份
+		if len(s.Bases) > 0 {
+			bases := make(map[string]Money, len(s.Bases))
+			for key := range s.Bases {
+				bases[key] = p.Base
+			}
+			s.Bases = bases
+		}
+		changes = append(changes, fmt.Sprintf("缴费基数调整为 %.2f", p.Base))
+	}
+	if p.FundRate > 0 {
+		s.AccumulationFundRate = p.FundRate
+		changes = append(changes, fmt.Sprintf("公积金比例调整为 %g%%", p.FundRate))
+	}
+	return changes
+}
+
+// Expand 展开为逐月的月工资，同时返回规则中的全年一次性奖金
+func (p *Schedule) Expand(year int) ([]MonthlySalary, *Bonus, error) {
+	from, to := p.From, p.To
+	if from.IsZero() {
+		from = YearMonth{Year: year, Month: 1}
+	}
+	if to.IsZero() {
+		to = YearMonth{Year: from.Year, Month: 12}
+	}
+	if to.Year != from.Year || to.Before(from) {
+		return nil, nil, fmt.Errorf("月工资计划的期间需在同一年度内: %s ~ %s", from, to)
+	}
+
+	rules := append([]ScheduleRule{}, p.Rules...)
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Month < rules[j].Month })
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, nil, err
+		}
+		if (rule.Extra > 0 || rule.Bonus > 0) && (rule.Month < from.Month || rule.Month > to.Month) {
+			return nil, nil, fmt.Errorf("%d 月发放的工资或奖金不在计划期间内", rule.Month)
+		}
+	}
+
+	var (
+		months []MonthlySalary
+		bonus  *Bonus
+	)
+	current := p.Template
+	// 起始月之前的规则只用于确定起始月的月工资
+	for month := 1; month <= to.Month; month++ {
+		var changes []string
+		for i := range rules {
+			if rules[i].Month == month {
+				changes = append(changes, rules[i].apply(&current)...)
+			}
+		}
+
+		s := current
+		for _, rule := range rules {
+			if rule.Month != month {
+				continue
+			}
+			if rule.Extra > 0 {
+				s.ExtraAmount += rule.Extra
+				changes = append(changes, fmt.Sprintf("额外发放 %.2f", rule.Extra))
+			}
+			if rule.Bonus > 0 {
+				if bonus != nil {
+					return nil, nil, fmt.Errorf("全年一次性奖金只能发放一次")
+				}
+				bonus = &Bonus{Amount: rule.Bonus, Month: month}
+				changes = append(changes, fmt.Sprintf("发放全年一次性奖金 %.2f", rule.Bonus))
+			}
+		}
+
+		if month < from.Month {
+			continue
+		}
+		s.Month = YearMonth{Year: from.Year, Month: month}
+		s.Changes = changes
+		months = append(months, s)
+
+		// 户口、养老类型的变更只需在起始月设置一次
+		current.Residence, current.Endowment = nil, nil
+	}
+
+	return months, bonus, nil
+}
+
+// Expand 按月工资计划展开，返回只包含逐月月工资的薪资配置，未配置计划时返回自身
+func (p *Salaries) Expand() (*Salaries, error) {
+	if p.Schedule == nil {
+		return p, nil
+	}
+	if len(p.MonthlySalaries) > 0 {
+		return nil, fmt.Errorf("schedule 与 monthly_salaries 不能同时配置")
+	}
+
+	months, bonus, err := p.Schedule.Expand(p.year())
+	if err != nil {
+		return nil, err
+	}
+
+	ss := *p
+	ss.For = false
+	ss.Schedule = nil
+	ss.MonthlySalaries = months
+	if bonus != nil {
+		if ss.Bonus != nil {
+			return nil, fmt.Errorf("bonus 与月工资计划中的奖金不能同时配置")
+		}
+		ss.Bonus = bonus
+	}
+	return &ss, nil
+}
+
+const (
+	printScheduleInfor = "%2d月, 月薪: %10.2f, 补贴: %10.2f, 额外工资: %10.2f, 公积金比例: %5.2f%%"
+)
+
+// SalarySchedule 展开后的月工资计划
+type SalarySchedule struct {
+	MonthlySalaries []MonthlySalary `yaml:"monthly_salaries" json:"monthly_salaries"`
+	Bonus           *Bonus          `yaml:"bonus,omitempty" json:"bonus,omitempty"`
+}
+
+// ExpandSchedule 按月工资计划展开，返回逐月的月工资
+func (p *Salaries) ExpandSchedule() (*SalarySchedule, error) {
+	ss, err := p.Expand()
+	if err != nil {
+		return nil, err
+	}
+	return &SalarySchedule{MonthlySalaries: ss.MonthlySalaries, Bonus: ss.Bonus}, nil
+}
+
+// Print 打印信息
+func (p *SalarySchedule) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *SalarySchedule) Fprint(w io.Writer) {
+	for _, s := range p.MonthlySalaries {
+		line := fmt.Sprintf(printScheduleInfor, s.Month.Month, s.Salary, s.SubsidyAmount,
+			s.ExtraAmount, s.AccumulationFundRate)
+		if s.AccumulationFundBase > 0 {
+			line += fmt.Sprintf(", 公积金基数: %.2f", s.AccumulationFundBase)
+		}
+		if len(s.Changes) > 0 {
+			line += fmt.Sprintf(", 调整: %s", strings.Join(s.Changes, "；"))
+		}
+		fmt.Fprintln(w, line)
+	}
+	if p.Bonus != nil && p.Bonus.Amount > 0 {
+		fmt.Fprintln(w, fmt.Sprintf("全年一次性奖金: %.2f, 发放月份: %d月", p.Bonus.Amount, p.Bonus.Month))
+	}
+}
+
+// Tables 转为表格
+func (p *SalarySchedule) Tables() []*Table {
+	t := &Table{
+		Title:   "月工资计划",
+		Headers: []string{"月份", "月薪", "补贴", "额外工资", "公积金比例", "公积金基数", "调整"},
+	}
+	var salary, subsidy, extra Money
+	for _, s := range p.MonthlySalaries {
+		salary += s.Salary
+		subsidy += s.SubsidyAmount
+		extra += s.ExtraAmount
+		t.AddRow(s.Month.String(), s.Salary.String(), s.SubsidyAmount.String(), s.ExtraAmount.String(),
+			rateCell(s.AccumulationFundRate), s.AccumulationFundBase.String(), strings.Join(s.Changes, "；"))
+	}
+	t.Totals = []string{"合计", salary.String(), subsidy.String(), extra.String(), "", "", ""}
+
+	tables := []*Table{t}
+	if p.Bonus != nil && p.Bonus.Amount > 0 {
+		bonus := &Table{
+			Title:   "全年一次性奖金",
+			Headers: []string{"发放月份", "金额"},
+		}
+		bonus.AddRow(strconv.Itoa(p.Bonus.Month), p.Bonus.Amount.String())
+		tables = append(tables, bonus)
+	}
+	return tables
+}
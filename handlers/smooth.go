@@ -0,0 +1,399 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// NetPaySmoothing 税后收入平滑方案
+type NetPaySmoothing struct {
+	// 公积金年度调整的月份，公积金比例只能从该月起调整
+	AdjustMonth int `yaml:"adjust_month" json:"adjust_month"`
+
+	Original *SmoothSchedule `yaml:"original" json:"original"`
+	Plan     *SmoothSchedule `yaml:"plan" json:"plan"`
+
+	// 方案相对原安排的调整
+	Suggestions []string `yaml:"suggestions" json:"suggestions"`
+}
+
+// SmoothSchedule 一种发放安排的计算结果
+type SmoothSchedule struct {
+	// 全年一次性奖金的发放月份，没有奖金时为 0
+	BonusMonth int `yaml:"bonus_month" json:"bonus_month"`
+
+	Taxation         Money `yaml:"taxation" json:"taxation"`
+	RestSalary       Money `yaml:"rest_salary" json:"rest_salary"`
+	AccumulationFund Money `yaml:"accumulation_fund" json:"accumulation_fund"`
+	// 月税后收入的标准差，以及最高与最低月份之差
+	StdDev Money `yaml:"std_dev" json:"std_dev"`
+	Spread Money `yaml:"spread" json:"spread"`
+
+	Months []*SmoothMonth `yaml:"months" json:"months"`
+
+	variance float64
+}
+
+// SmoothMonth 某月的发放安排
+type SmoothMonth struct {
+	Year  int `yaml:"year" json:"year"`
+	Month int `yaml:"month" json:"month"`
+
+	SubsidyAmount        Money   `yaml:"subsidy_amount" json:"subsidy_amount"`
+	Bonus                Money   `yaml:"bonus" json:"bonus"`
+	AccumulationFundRate float64 `yaml:"accumulation_fund_rate" json:"accumulation_fund_rate"`
+	AccumulationFund     Money   `yaml:"accumulation_fund" json:"accumulation_fund"`
+	// 含单独计税的奖金个税
+	Taxation   Money `yaml:"taxation" json:"taxation"`
+	RestSalary Money `yaml:"rest_salary" json:"rest_salary"`
+}
+
+// smoothOption 可调整的发放安排
+type smoothOption struct {
+	bonusMonth int
+	// 调整月起的公积金比例，为 0 时不调整
+	fundRate  float64
+	subsidies []Money
+}
+
+// SmoothNetPay 在全年个税不增加的前提下，调整年终奖发放月份、补贴发放节奏以及公积金年度调整时的比例，
+// 使每月税后收入尽量平稳
+func (p *TaxesHandler) SmoothNetPay(salaries *Salaries, adjustMonth int) (*NetPaySmoothing, error) {
+	if adjustMonth < 1 || adjustMonth > 12 {
+		return nil, fmt.Errorf("公积金调整月份需在 1 和 12 之间: %d", adjustMonth)
+	}
+	base, err := salaries.fixedMonths()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &smoother{handler: p, salaries: base, adjustMonth: adjustMonth}
+	current := smoothOption{subsidies: make([]Money, len(base.MonthlySalaries))}
+	for i, m := range base.MonthlySalaries {
+		current.subsidies[i] = m.SubsidyAmount
+	}
+	if base.Bonus != nil && base.Bonus.Amount > 0 {
+		current.bonusMonth = base.Bonus.Month
+	}
+
+	original, err := s.evaluate(current)
+	if err != nil {
+		return nil, err
+	}
+	s.maxTaxation = original.Taxation
+
+	fundRates, err := s.fundRates()
+	if err != nil {
+		return nil, err
+	}
+
+	best, plan := current, original
+	// try 方案更平稳时采用，返回是否采用
+	try := func(option smoothOption) bool {
+		result, err := s.evaluate(option)
+		// 无法计算或个税增加的方案直接跳过
+		if err != nil || result.Taxation > s.maxTaxation || result.variance >= plan.variance {
+			return false
+		}
+		best, plan = option, result
+		return true
+	}
+
+	// 逐项调整，直到没有更平稳的方案
+	for round := 0; round < 3; round++ {
+		variance := plan.variance
+		if best.bonusMonth > 0 {
+			for _, m := range base.MonthlySalaries {
+				option := best
+				option.bonusMonth = m.Month.Month
+				try(option)
+			}
+		}
+		for _, rate := range fundRates {
+			option := best
+			option.fundRate = rate
+			try(option)
+		}
+		// 补贴调整后各月个税也会变化，按新的结果重新分配，直到不再更平稳
+		for i := 0; i < 5; i++ {
+			option := best
+			option.subsidies = fillSubsidies(plan, sumMoney(current.subsidies))
+			if sameMoney(option.subsidies, best.subsidies) || !try(option) {
+				break
+			}
+		}
+		if plan.variance >= variance {
+			break
+		}
+	}
+
+	return &NetPaySmoothing{
+		AdjustMonth: adjustMonth,
+		Original:    original,
+		Plan:        plan,
+		Suggestions: s.suggestions(current, best, original, plan),
+	}, nil
+}
+
+type smoother struct {
+	handler     *TaxesHandler
+	salaries    *Salaries
+	adjustMonth int
+	maxTaxation Money
+}
+
+// apply 按发放安排生成薪资配置
+func (p *smoother) apply(option smoothOption) *Salaries {
+	i := 0
+	ss := p.salaries.eachMonth(func(s *MonthlySalary) {
+		s.SubsidyAmount = option.subsidies[i]
+		if option.fundRate > 0 && s.Month.Month >= p.adjustMonth {
+			s.AccumulationFundRate = option.fundRate
+		}
+		i++
+	})
+	if option.bonusMonth > 0 {
+		bonus := *p.salaries.Bonus
+		bonus.Month = option.bonusMonth
+		ss.Bonus = &bonus
+	}
+	return ss
+}
+
+func (p *smoother) evaluate(option smoothOption) (*SmoothSchedule, error) {
+	taxes, err := p.handler.Calc(p.apply(option))
+	if err != nil {
+		return nil, err
+	}
+	result := &SmoothSchedule{BonusMonth: option.bonusMonth}
+	var min, max Money
+	for i, t := range taxes.Taxes {
+		rate := 0.0
+		if t.AccumulationFundResult != nil {
+			rate = t.AccumulationFundResult.Rate
+		}
+		result.Months = append(result.Months, &SmoothMonth{
+			Year:                 t.Year,
+			Month:                t.Month,
+			SubsidyAmount:        t.SubsidyAmount,
+			Bonus:                t.Bonus,
+			AccumulationFundRate: rate,
+			AccumulationFund:     t.AccumulationFund,
+			Taxation:             t.Taxation + t.BonusTaxation,
+			RestSalary:           t.RestSalary,
+		})
+		result.AccumulationFund += t.AccumulationFund
+		if i == 0 || t.RestSalary < min {
+			min = t.RestSalary
+		}
+		if i == 0 || t.RestSalary > max {
+			max = t.RestSalary
+		}
+	}
+	result.Taxation = taxes.TotalTaxation()
+	result.RestSalary = taxes.TotalRestSalary()
+	result.Spread = max - min
+
+	mean := result.RestSalary.Yuan() / float64(len(taxes.Taxes))
+	for _, t := range taxes.Taxes {
+		d := t.RestSalary.Yuan() - mean
+		result.variance += d * d
+	}
+	result.variance /= float64(len(taxes.Taxes))
+	result.StdDev = NewMoney(math.Sqrt(result.variance))
+	return result, nil
+}
+
+// fundRates 公积金调整月份适用政策允许的各个整数比例，调整月份没有月工资时不调整
+func (p *smoother) fundRates() ([]float64, error) {
+	if !p.salaries.hasMonth(p.salaries.MonthlySalaries, p.adjustMonth) {
+		return nil, nil
+	}
+	h, err := p.handler.Handler(p.salaries.PersonalInfo.Jurisdiction, p.salaries.yearMonth(p.adjustMonth))
+	if err != nil {
+		return nil, err
+	}
+	var rates []float64
+	for rate := math.Ceil(h.AccumulationFundBase.MinRate); rate <= h.AccumulationFundBase.MaxRate; rate++ {
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// fillSubsidies 全年补贴合计不变，优先补给扣除补贴后税后收入最低的月份，使各月尽量持平
+func fillSubsidies(result *SmoothSchedule, total Money) []Money {
+	bases := make([]Money, len(result.Months))
+	for i, m := range result.Months {
+		bases[i] = m.RestSalary - m.SubsidyAmount
+	}
+	filled := func(level Money) Money {
+		var sum Money
+		for _, b := range bases {
+			if b < level {
+				sum += level - b
+			}
+		}
+		return sum
+	}
+
+	sorted := append([]Money{}, bases...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	// 找出补足后各月持平的最高水位
+	lo, hi := sorted[0], sorted[len(sorted)-1]+total
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if filled(mid) <= total {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	subsidies := make([]Money, len(bases))
+	rest := total - filled(lo)
+	for i, b := range bases {
+		if b < lo {
+			subsidies[i] = lo - b
+		}
+		// 不足一分的余数依次补给达到水位的月份
+		if b <= lo && rest > 0 {
+			subsidies[i]++
+			rest--
+		}
+	}
+	return subsidies
+}
+
+func sameMoney(a, b []Money) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sumMoney(amounts []Money) Money {
+	var total Money
+	for _, amount := range amounts {
+		total += amount
+	}
+	return total
+}
+
+// suggestions 方案相对原安排的调整说明
+func (p *smoother) suggestions(current, best smoothOption, original, plan *SmoothSchedule) []string {
+	var suggestions []string
+	if best.bonusMonth != current.bonusMonth {
+		suggestions = append(suggestions, fmt.Sprintf("全年一次性奖金改在 %d 月发放", best.bonusMonth))
+	}
+	if best.fundRate > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("%d 月公积金年度调整时，比例调整为 %g%%", p.adjustMonth, best.fundRate))
+	}
+	for i, amount := range best.subsidies {
+		if amount != current.subsidies[i] {
+			suggestions = append(suggestions, fmt.Sprintf("补贴按月调整发放，全年合计 %.2f 不变", sumMoney(current.subsidies)))
+			break
+		}
+	}
+	if len(suggestions) == 0 {
+		return []string{"当前安排已是最平稳的方案"}
+	}
+	line := fmt.Sprintf("月税后收入标准差从 %.2f 降至 %.2f", original.StdDev, plan.StdDev)
+	if plan.Taxation < original.Taxation {
+		line += fmt.Sprintf("，全年个税减少 %.2f", original.Taxation-plan.Taxation)
+	}
+	return append(suggestions, line)
+}
+
+const (
+	printSmoothSchedule = "%s: 全年个税: %10.2f, 全年税后收入: %12.2f, 个人公积金: %10.2f, 月税后收入标准差: %9.2f, 最高最低相差: %9.2f"
+	printSmoothMonth    = "补贴: %8.2f, 奖金: %10.2f, 公积金: %8.2f(%g%%), 个税: %9.2f, 税后: %10.2f"
+)
+
+// Print 打印信息
+func (p *NetPaySmoothing) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *NetPaySmoothing) Fprint(w io.Writer) {
+	p.Original.print(w, "调整前")
+	p.Plan.print(w, "调整后")
+	fmt.Fprintln(w, "建议:")
+	for _, s := range p.Suggestions {
+		fmt.Fprintln(w, "\t"+s)
+	}
+	for i, o := range p.Original.Months {
+		n := p.Plan.Months[i]
+		fmt.Fprintln(w, fmt.Sprintf("%2d月, 调整前 "+printSmoothMonth+" | 调整后 "+printSmoothMonth, o.Month,
+			o.SubsidyAmount, o.Bonus, o.AccumulationFund, o.AccumulationFundRate, o.Taxation, o.RestSalary,
+			n.SubsidyAmount, n.Bonus, n.AccumulationFund, n.AccumulationFundRate, n.Taxation, n.RestSalary))
+	}
+}
+
+func (p *SmoothSchedule) print(w io.Writer, name string) {
+	fmt.Fprintln(w, fmt.Sprintf(printSmoothSchedule, name, p.Taxation, p.RestSalary, p.AccumulationFund, p.StdDev, p.Spread))
+}
+
+// Tables 转为表格
+func (p *NetPaySmoothing) Tables() []*Table {
+	summary := &Table{
+		Title:   "税后收入平滑",
+		Headers: []string{"方案", "奖金月份", "全年个税", "全年税后收入", "个人公积金", "月税后收入标准差", "最高最低相差"},
+	}
+	for _, c := range []struct {
+		name     string
+		schedule *SmoothSchedule
+	}{{"调整前", p.Original}, {"调整后", p.Plan}} {
+		month := ""
+		if c.schedule.BonusMonth > 0 {
+			month = fmt.Sprintf("%d", c.schedule.BonusMonth)
+		}
+		summary.AddRow(c.name, month, c.schedule.Taxation.String(), c.schedule.RestSalary.String(),
+			c.schedule.AccumulationFund.String(), c.schedule.StdDev.String(), c.schedule.Spread.String())
+	}
+
+	months := &Table{
+		Title: "逐月对比",
+		Headers: []string{"月份",
+			"调整前补贴", "调整前奖金", "调整前公积金", "调整前个税", "调整前税后",
+			"调整后补贴", "调整后奖金", "调整后公积金", "调整后个税", "调整后税后", "税后差额"},
+	}
+	for i, o := range p.Original.Months {
+		n := p.Plan.Months[i]
+		months.AddRow(append([]string{monthCell(o.Year, o.Month)}, moneyCells(
+			o.SubsidyAmount, o.Bonus, o.AccumulationFund, o.Taxation, o.RestSalary,
+			n.SubsidyAmount, n.Bonus, n.AccumulationFund, n.Taxation, n.RestSalary,
+			n.RestSalary-o.RestSalary)...)...)
+	}
+
+	suggestions := &Table{Title: "建议", Headers: []string{"调整"}}
+	for _, s := range p.Suggestions {
+		suggestions.AddRow(s)
+	}
+	return []*Table{summary, months, suggestions}
+}
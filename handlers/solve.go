@@ -0,0 +1,271 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// NetSolution 税后反推税前的结果
+type NetSolution struct {
+	// 目标税后收入
+	Net Money `yaml:"net" json:"net"`
+	// 目标月份，0 表示按全年的月平均税后收入
+	Month int `yaml:"month" json:"month"`
+
+	// 税前月薪，有多个解时为最低的一个
+	Gross Money `yaml:"gross" json:"gross"`
+	// 按该月薪计算出的税后收入，不低于目标
+	Result Money `yaml:"result" json:"result"`
+
+	// 是否只有一个解
+	Unique bool `yaml:"unique" json:"unique"`
+	// 所有的解，按税前月薪从低到高排列
+	Solutions []*NetSolutionPoint `yaml:"solutions" json:"solutions"`
+
+	// 按 Gross 计算的逐月明细
+	Taxes *MonthlyTaxes `yaml:"taxes" json:"taxes"`
+}
+
+// NetSolutionPoint 一个税前月薪及其税后收入
+type NetSolutionPoint struct {
+	Gross Money `yaml:"gross" json:"gross"`
+	Net   Money `yaml:"net" json:"net"`
+}
+
+const (
+	// maxSolveGross 反推的税前月薪上限
+	maxSolveGross = 100000000 * Yuan
+	// solveSamples 扫描的采样点数，相邻采样点之间用二分法定位
+	solveSamples = 1000
+)
+
+// SolveNet 反推税后收入为 net 所需的税前月薪，计算时每月月薪都替换为同一个值，不计全年一次性奖金
+// month 为 1~12 时按该月的税后收入，为 0 时按全年的月平均税后收入
+//
+// 税后收入随月薪分段变化：缴费基数到达上下限、累计应纳税所得额跨越税率级距时斜率改变；
+// 累计预扣法下，跨越级距的月份多发的工资可能不够多交的个税，税后收入反而下降，因此可能有多个解。
+// 先倍增找到上界，在 [0, 2×上界] 内均匀采样，再对每个跨越目标的采样区间二分到分
+func (p *TaxesHandler) SolveNet(salaries *Salaries, net Money, month int) (*NetSolution, error) {
+	if net <= 0 {
+		return nil, fmt.Errorf("税后收入需大于0")
+	}
+	if month < 0 || month > 12 {
+		return nil, fmt.Errorf("月份需在 1 和 12 之间，0 表示全年平均")
+	}
+
+	ss, err := salaries.fixedMonths()
+	if err != nil {
+		return nil, err
+	}
+	ss.Bonus = nil
+	if month > 0 && !ss.hasMonth(ss.MonthlySalaries, month) {
+		return nil, fmt.Errorf("%d 月没有月工资", month)
+	}
+
+	solver := &netSolver{handler: p, salaries: ss, month: month}
+
+	hi := net
+	for {
+		result, err := solver.net(hi)
+		if err != nil {
+			return nil, err
+		}
+		if result >= net {
+			break
+		}
+		if hi > maxSolveGross {
+			return nil, fmt.Errorf("税前月薪超过 %.2f 仍无法达到税后 %.2f", maxSolveGross, net)
+		}
+		hi = hi.Mul(2)
+	}
+
+	solution := &NetSolution{Net: net, Month: month}
+	// 采样步长至少为 1 元
+	step := hi.Mul(2) / solveSamples
+	if step < Yuan {
+		step = Yuan
+	}
+
+	prev, err := solver.reaches(0, net)
+	if err != nil {
+		return nil, err
+	}
+	for gross := step; gross <= hi.Mul(2); gross += step {
+		reached, err := solver.reaches(gross, net)
+		if err != nil {
+			return nil, err
+		}
+		if reached != prev {
+			point, err := solver.bisect(gross-step, gross, net, reached)
+			if err != nil {
+				return nil, err
+			}
+			solution.Solutions = append(solution.Solutions, point)
+		}
+		prev = reached
+	}
+	if len(solution.Solutions) == 0 {
+		return nil, fmt.Errorf("无法反推税后 %.2f 的税前月薪", net)
+	}
+
+	best := solution.Solutions[0]
+	solution.Gross, solution.Result = best.Gross, best.Net
+	solution.Unique = len(solution.Solutions) == 1
+	solution.Taxes, err = p.Calc(solver.with(best.Gross))
+	if err != nil {
+		return nil, err
+	}
+	return solution, nil
+}
+
+type netSolver struct {
+	handler  *TaxesHandler
+	salaries *Salaries
+	month    int
+}
+
+// with 每月月薪都为 gross 的薪资配置
+func (p *netSolver) with(gross Money) *Salaries {
+	return p.salaries.eachMonth(func(s *MonthlySalary) { s.Salary = gross })
+}
+
+// fixedMonths 展开月工资计划并确定每条月工资的年月，For 补足的月份也列出，便于逐月替换
+func (p *Salaries) fixedMonths() (*Salaries, error) {
+	salaries, err := p.Expand()
+	if err != nil {
+		return nil, err
+	}
+	if len(salaries.MonthlySalaries) == 0 {
+		return nil, fmt.Errorf("未配置月工资")
+	}
+	months, err := salaries.months()
+	if err != nil {
+		return nil, err
+	}
+	for i := range months {
+		months[i].projected = false
+	}
+	ss := *salaries
+	ss.For = false
+	ss.MonthlySalaries = months
+	return &ss, nil
+}
+
+// eachMonth 复制薪资配置，并用 fn 修改每条月工资
+func (p *Salaries) eachMonth(fn func(s *MonthlySalary)) *Salaries {
+	ss := *p
+	ss.MonthlySalaries = make([]MonthlySalary, len(p.MonthlySalaries))
+	for i, s := range p.MonthlySalaries {
+		fn(&s)
+		ss.MonthlySalaries[i] = s
+	}
+	return &ss
+}
+
+// net 月薪为 gross 时目标月份或全年平均的税后收入
+func (p *netSolver) net(gross Money) (Money, error) {
+	taxes, err := p.handler.Calc(p.with(gross))
+	if err != nil {
+		return 0, err
+	}
+	if p.month == 0 {
+		return taxes.TotalRestSalary().Div(int64(len(taxes.Taxes)), RoundTruncate), nil
+	}
+	for _, t := range taxes.Taxes {
+		if t.Month == p.month {
+			return t.RestSalary, nil
+		}
+	}
+	return 0, fmt.Errorf("%d 月没有月工资", p.month)
+}
+
+func (p *netSolver) reaches(gross, net Money) (bool, error) {
+	result, err := p.net(gross)
+	return result >= net, err
+}
+
+// bisect 在 (lo, hi] 内定位达到目标的边界：上升时为达到目标的最低月薪，下降时为仍达到目标的最高月薪
+func (p *netSolver) bisect(lo, hi, net Money, rising bool) (*NetSolutionPoint, error) {
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		reached, err := p.reaches(mid, net)
+		if err != nil {
+			return nil, err
+		}
+		if reached == rising {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	gross := hi
+	if !rising {
+		gross = lo
+	}
+	result, err := p.net(gross)
+	if err != nil {
+		return nil, err
+	}
+	return &NetSolutionPoint{Gross: gross, Net: result}, nil
+}
+
+// Print 打印信息
+func (p *NetSolution) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *NetSolution) Fprint(w io.Writer) {
+	fmt.Fprintln(w, fmt.Sprintf("目标税后: %.2f (%s)", p.Net, p.target()))
+	fmt.Fprintln(w, fmt.Sprintf("税前月薪: %.2f, 实际税后: %.2f", p.Gross, p.Result))
+	if p.Unique {
+		fmt.Fprintln(w, "唯一解")
+	} else {
+		fmt.Fprintln(w, fmt.Sprintf("存在 %d 个解（累计预扣跨越税率级距时，税后收入可能随税前增加而减少）:", len(p.Solutions)))
+		for _, s := range p.Solutions {
+			fmt.Fprintln(w, fmt.Sprintf("\t税前月薪: %.2f, 税后: %.2f", s.Gross, s.Net))
+		}
+	}
+	p.Taxes.Fprint(w)
+}
+
+func (p *NetSolution) target() string {
+	if p.Month == 0 {
+		return "全年月平均"
+	}
+	return fmt.Sprintf("%d月", p.Month)
+}
+
+// Tables 转为表格：所有的解，以及最低解的逐月明细
+func (p *NetSolution) Tables() []*Table {
+	unique := "否"
+	if p.Unique {
+		unique = "是"
+	}
+	solutions := &Table{
+		Title:   fmt.Sprintf("目标税后: %.2f (%s), 唯一解: %s", p.Net, p.target(), unique),
+		Headers: []string{"税前月薪", "税后"},
+	}
+	for _, s := range p.Solutions {
+		solutions.AddRow(s.Gross.String(), s.Net.String())
+	}
+	return append([]*Table{solutions}, p.Taxes.Tables()...)
+}
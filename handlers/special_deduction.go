@@ -0,0 +1,196 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+)
+
+// CityTier 住房租金扣除的城市类别
+type CityTier string
+
+// 住房租金城市类别
+const (
+	// 直辖市、省会城市、计划单列市等
+	CityTier1 CityTier = "tier1"
+	// 市辖区户籍人口超过100万的城市
+	CityTier2 CityTier = "tier2"
+	// 市辖区户籍人口不超过100万的城市
+	CityTier3 CityTier = "tier3"
+)
+
+// SpecialDeductionStandard 某一政策年度的专项附加扣除标准
+type SpecialDeductionStandard struct {
+	// 自该年度起执行
+	Year int `yaml:"year" json:"year"`
+
+	ChildrenEducation              Money `yaml:"children_education" json:"children_education"`                             // 子女教育，每个子女每月
+	InfantCare                     Money `yaml:"infant_care" json:"infant_care"`                                           // 3岁以下婴幼儿照护，每个婴幼儿每月
+	ContinuingEducationDegree      Money `yaml:"continuing_education_degree" json:"continuing_education_degree"`           // 学历继续教育，每月
+	ContinuingEducationCertificate Money `yaml:"continuing_education_certificate" json:"continuing_education_certificate"` // 职业资格继续教育，取得证书当年
+	HousingLoanInterest            Money `yaml:"housing_loan_interest" json:"housing_loan_interest"`                       // 住房贷款利息，每月
+
+	HousingRent map[CityTier]Money `yaml:"housing_rent" json:"housing_rent"` // 住房租金，每月
+
+	ElderlySupport         Money `yaml:"elderly_support" json:"elderly_support"`                     // 赡养老人，每月
+	ElderlySupportShareMax Money `yaml:"elderly_support_share_max" json:"elderly_support_share_max"` // 非独生子女每人每月分摊上限
+
+	SeriousIllnessThreshold Money `yaml:"serious_illness_threshold" json:"serious_illness_threshold"` // 大病医疗，超过该金额的部分可扣除
+	SeriousIllnessMax       Money `yaml:"serious_illness_max" json:"serious_illness_max"`             // 大病医疗，每年扣除上限
+}
+
+// SpecialDeductions 个人的专项附加扣除信息
+type SpecialDeductions struct {
+	// 接受学历教育的子女数
+	ChildrenEducation int `yaml:"children_education" json:"children_education"`
+	// 3岁以下婴幼儿数
+	InfantCare int `yaml:"infant_care" json:"infant_care"`
+	// 子女教育、婴幼儿照护的扣除比例，100 本人全额扣除（默认），50 父母各扣一半
+	ChildrenShare float64 `yaml:"children_share" json:"children_share"`
+
+	// 学历（学位）继续教育
+	ContinuingEducationDegree bool `yaml:"continuing_education_degree" json:"continuing_education_degree"`
+	// 当年取得职业资格继续教育证书
+	ContinuingEducationCertificate bool `yaml:"continuing_education_certificate" json:"continuing_education_certificate"`
+
+	// 首套住房贷款利息
+	HousingLoanInterest bool `yaml:"housing_loan_interest" json:"housing_loan_interest"`
+	// 住房贷款利息扣除比例，100 本人全额扣除（默认），50 夫妻婚前各自购房、婚后分别扣除
+	HousingLoanShare float64 `yaml:"housing_loan_share" json:"housing_loan_share"`
+	// 住房租金所在城市类别，为空表示不扣除
+	HousingRent CityTier `yaml:"housing_rent" json:"housing_rent"`
+
+	// 赡养老人
+	ElderlySupport bool `yaml:"elderly_support" json:"elderly_support"`
+	// 是否独生子女
+	ElderlyOnlyChild bool `yaml:"elderly_only_child" json:"elderly_only_child"`
+	// 非独生子女每月分摊的金额
+	ElderlyShare Money `yaml:"elderly_share" json:"elderly_share"`
+
+	// 大病医疗全年自付金额（医保目录范围内）
+	SeriousIllness Money `yaml:"serious_illness" json:"serious_illness"`
+}
+
+// SpecialDeductionStandard 查找适用于该年度的专项附加扣除标准，year 为 0 时使用最新标准
+func (p *YearTaxBase) SpecialDeductionStandard(year int) (*SpecialDeductionStandard, error) {
+	var standard *SpecialDeductionStandard
+	for i, s := range p.SpecialDeductionStandards {
+		if year != 0 && s.Year > year {
+			continue
+		}
+		if standard == nil || s.Year > standard.Year {
+			standard = &p.SpecialDeductionStandards[i]
+		}
+	}
+	if standard == nil {
+		return nil, fmt.Errorf("未找到 %d 年度的专项附加扣除标准", year)
+	}
+	return standard, nil
+}
+
+// Validate 校验专项附加扣除信息
+func (p *SpecialDeductions) Validate() error {
+	if p.ChildrenEducation < 0 || p.InfantCare < 0 {
+		return fmt.Errorf("子女数不能小于0")
+	}
+	if !validShare(p.ChildrenShare) {
+		return fmt.Errorf("子女教育扣除比例只能是 50 或 100")
+	}
+	if !validShare(p.HousingLoanShare) {
+		return fmt.Errorf("住房贷款利息扣除比例只能是 50 或 100")
+	}
+	if p.HousingLoanInterest && p.HousingRent != "" {
+		return fmt.Errorf("住房贷款利息与住房租金不能同时扣除")
+	}
+	if p.ElderlyShare < 0 || p.SeriousIllness < 0 {
+		return fmt.Errorf("扣除金额不能小于0")
+	}
+	return nil
+}
+
+func validShare(share float64) bool {
+	return share == 0 || share == 50 || share == 100
+}
+
+// sharePercent 扣除比例，未设置时全额扣除
+func sharePercent(share float64) float64 {
+	if share == 0 {
+		return 100
+	}
+	return share
+}
+
+// Monthly 按月计算的专项附加扣除合计
+func (p *SpecialDeductions) Monthly(standard *SpecialDeductionStandard) (Money, error) {
+	if err := p.Validate(); err != nil {
+		return 0, err
+	}
+
+	children := standard.ChildrenEducation.Mul(int64(p.ChildrenEducation)) +
+		standard.InfantCare.Mul(int64(p.InfantCare))
+	amount := children.MulRate(sharePercent(p.ChildrenShare), RoundHalfUp)
+
+	if p.ContinuingEducationDegree {
+		amount += standard.ContinuingEducationDegree
+	}
+
+	if p.HousingLoanInterest {
+		amount += standard.HousingLoanInterest.MulRate(sharePercent(p.HousingLoanShare), RoundHalfUp)
+	}
+	if p.HousingRent != "" {
+		rent, ok := standard.HousingRent[p.HousingRent]
+		if !ok {
+			return 0, fmt.Errorf("未知的住房租金城市类别: %s", p.HousingRent)
+		}
+		amount += rent
+	}
+
+	if p.ElderlySupport {
+		if p.ElderlyOnlyChild {
+			amount += standard.ElderlySupport
+		} else {
+			if p.ElderlyShare > standard.ElderlySupportShareMax {
+				return 0, fmt.Errorf("非独生子女每月分摊的赡养老人扣除不能超过 %.2f", standard.ElderlySupportShareMax)
+			}
+			amount += p.ElderlyShare
+		}
+	}
+
+	return amount, nil
+}
+
+// Annual 只在年度汇算时扣除的专项附加扣除合计，包括职业资格继续教育和大病医疗
+func (p *SpecialDeductions) Annual(standard *SpecialDeductionStandard) (Money, error) {
+	if err := p.Validate(); err != nil {
+		return 0, err
+	}
+
+	var amount Money
+	if p.ContinuingEducationCertificate {
+		amount += standard.ContinuingEducationCertificate
+	}
+
+	if illness := p.SeriousIllness - standard.SeriousIllnessThreshold; illness > 0 {
+		if illness > standard.SeriousIllnessMax {
+			illness = standard.SeriousIllnessMax
+		}
+		amount += illness
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,278 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/ymhhh/tax/chart"
+)
+
+// SweepParam 扫描的参数
+type SweepParam string
+
+// 扫描的参数
+const (
+	SweepSalary   SweepParam = "salary"    // 每月月薪
+	SweepFundRate SweepParam = "fund_rate" // 公积金比例
+	SweepBonus    SweepParam = "bonus"     // 全年一次性奖金
+)
+
+// SweepParamName 参数的中文名称
+func SweepParamName(param SweepParam) string {
+	switch param {
+	case SweepSalary:
+		return "月薪"
+	case SweepFundRate:
+		return "公积金比例"
+	case SweepBonus:
+		return "全年一次性奖金"
+	}
+	return string(param)
+}
+
+// SweepRange 扫描的范围，包含 From 和 To
+type SweepRange struct {
+	From float64 `yaml:"from" json:"from"`
+	To   float64 `yaml:"to" json:"to"`
+	Step float64 `yaml:"step" json:"step"`
+}
+
+// maxSweepPoints 扫描的点数上限
+const maxSweepPoints = 10000
+
+// ParseSweepRange 解析 from:to:step 格式的范围
+func ParseSweepRange(s string) (SweepRange, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return SweepRange{}, fmt.Errorf("范围格式错误: %s, 应为 from:to:step", s)
+	}
+	var values [3]float64
+	for i, part := range parts {
+		if _, err := fmt.Sscan(strings.TrimSpace(part), &values[i]); err != nil {
+			return SweepRange{}, fmt.Errorf("范围格式错误: %s, 应为 from:to:step", s)
+		}
+	}
+	r := SweepRange{From: values[0], To: values[1], Step: values[2]}
+	return r, r.Validate()
+}
+
+// Validate 校验范围
+func (p SweepRange) Validate() error {
+	if p.Step <= 0 {
+		return fmt.Errorf("步长需大于0")
+	}
+	if p.From < 0 || p.To < p.From {
+		return fmt.Errorf("范围需满足 0 <= from <= to")
+	}
+	if (p.To-p.From)/p.Step >= maxSweepPoints {
+		return fmt.Errorf("扫描点数不能超过 %d", maxSweepPoints)
+	}
+	return nil
+}
+
+// Values 范围内的各个取值
+func (p SweepRange) Values() []float64 {
+	n := int(math.Floor((p.To-p.From)/p.Step+1e-9)) + 1
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = p.From + float64(i)*p.Step
+	}
+	return values
+}
+
+// SweepResult 扫描结果
+type SweepResult struct {
+	Param  SweepParam    `yaml:"param" json:"param"`
+	Points []*SweepPoint `yaml:"points" json:"points"`
+}
+
+// SweepPoint 参数取某个值时的全年结果
+type SweepPoint struct {
+	Value float64 `yaml:"value" json:"value"`
+
+	// 全年税前收入：月薪、补贴、额外工资及全年一次性奖金
+	Gross            Money `yaml:"gross" json:"gross"`
+	Insurances       Money `yaml:"insurances" json:"insurances"`
+	AccumulationFund Money `yaml:"accumulation_fund" json:"accumulation_fund"`
+	Taxation         Money `yaml:"taxation" json:"taxation"`
+
+	// 实际税率：全年个税占税前收入的百分比
+	EffectiveRate float64 `yaml:"effective_rate" json:"effective_rate"`
+	// 边际税率：每月月薪多发 100 元时，多交的个税占多发收入的百分比，包含社保、公积金的影响；
+	// 扫描奖金时为奖金多发 100 元时多交的个税占 100 元的百分比
+	MarginalRate float64 `yaml:"marginal_rate" json:"marginal_rate"`
+
+	Net        Money `yaml:"net" json:"net"`                 // 全年税后收入
+	MonthlyNet Money `yaml:"monthly_net" json:"monthly_net"` // 月平均税后收入
+	// 单位的用工成本：税前收入加上单位社保和公积金
+	EmployerCost Money `yaml:"employer_cost" json:"employer_cost"`
+}
+
+// marginalDelta 计算边际税率时每月月薪增加的金额，扫描奖金时为奖金增加的金额
+const marginalDelta = 100 * Yuan
+
+// Sweep 按范围逐个替换参数，计算全年的税率、税后收入和用工成本
+// 月薪、公积金比例替换每个月的值；奖金沿用配置的发放月份和计税方式，未配置时在最后一个月单独计税
+func (p *TaxesHandler) Sweep(salaries *Salaries, param SweepParam, r SweepRange) (*SweepResult, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	ss, err := salaries.fixedMonths()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SweepResult{Param: param}
+	for _, value := range r.Values() {
+		var s *Salaries
+		switch param {
+		case SweepSalary:
+			s = ss.eachMonth(func(m *MonthlySalary) { m.Salary = NewMoney(value) })
+		case SweepFundRate:
+			s = ss.eachMonth(func(m *MonthlySalary) { m.AccumulationFundRate = value })
+		case SweepBonus:
+			s = ss.withBonus(NewMoney(value))
+		default:
+			return nil, fmt.Errorf("不支持扫描的参数: %s", param)
+		}
+
+		point, err := p.sweepPoint(s, param, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s 为 %v 时: %v", SweepParamName(param), value, err)
+		}
+		result.Points = append(result.Points, point)
+	}
+	return result, nil
+}
+
+// withBonus 替换全年一次性奖金的金额
+func (p *Salaries) withBonus(amount Money) *Salaries {
+	ss := *p
+	bonus := Bonus{Month: p.MonthlySalaries[len(p.MonthlySalaries)-1].Month.Month, Method: BonusSeparate}
+	if p.Bonus != nil {
+		bonus = *p.Bonus
+	}
+	bonus.Amount = amount
+	ss.Bonus = &bonus
+	return &ss
+}
+
+func (p *TaxesHandler) sweepPoint(salaries *Salaries, param SweepParam, value float64) (*SweepPoint, error) {
+	taxes, err := p.Calc(salaries)
+	if err != nil {
+		return nil, err
+	}
+	// 边际税率按多发 marginalDelta 重新计算的差额得出：扫描奖金时只增加奖金，奖金的临界点会体现在差额中；
+	// 否则每月月薪都增加，社保公积金基数的上下限也会体现在差额中
+	raise := salaries.eachMonth(func(m *MonthlySalary) { m.Salary += marginalDelta })
+	delta := marginalDelta.Mul(int64(len(taxes.Taxes)))
+	if param == SweepBonus {
+		raise = salaries.withBonus(salaries.Bonus.Amount + marginalDelta)
+		delta = marginalDelta
+	}
+	raised, err := p.Calc(raise)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := taxes.Totals()
+	point := &SweepPoint{
+		Value:            value,
+		Gross:            totals.Income,
+		Insurances:       totals.Insurances,
+		AccumulationFund: totals.AccumulationFund,
+		Taxation:         totals.Taxation,
+		Net:              totals.RestSalary,
+		EmployerCost:     totals.CompanyCost,
+	}
+	point.MonthlyNet = point.Net.Div(int64(len(taxes.Taxes)), RoundHalfUp)
+	if point.Gross > 0 {
+		point.EffectiveRate = roundRate(point.Taxation.Yuan() / point.Gross.Yuan() * 100)
+	}
+	point.MarginalRate = roundRate((raised.TotalTaxation() - point.Taxation).Yuan() / delta.Yuan() * 100)
+	return point, nil
+}
+
+// roundRate 百分比保留两位小数
+func roundRate(rate float64) float64 {
+	return math.Round(rate*100) / 100
+}
+
+const (
+	printSweepPoint = "%s: %10.2f, 全年税前: %12.2f, 个税: %11.2f, 实际税率: %6.2f%%, 边际税率: %6.2f%%, 全年税后: %12.2f, 月均税后: %10.2f, 用工成本: %12.2f"
+)
+
+// Print 打印信息
+func (p *SweepResult) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *SweepResult) Fprint(w io.Writer) {
+	name := SweepParamName(p.Param)
+	for _, point := range p.Points {
+		fmt.Fprintln(w, fmt.Sprintf(printSweepPoint, name, point.Value, point.Gross, point.Taxation,
+			point.EffectiveRate, point.MarginalRate, point.Net, point.MonthlyNet, point.EmployerCost))
+	}
+}
+
+// Tables 转为表格
+func (p *SweepResult) Tables() []*Table {
+	t := &Table{
+		Title: fmt.Sprintf("按%s扫描", SweepParamName(p.Param)),
+		Headers: []string{SweepParamName(p.Param), "全年税前", "个人社保", "个人公积金", "个税",
+			"实际税率", "边际税率", "全年税后", "月均税后", "用工成本"},
+	}
+	for _, point := range p.Points {
+		t.AddRow(rateCell(point.Value), point.Gross.String(), point.Insurances.String(),
+			point.AccumulationFund.String(), point.Taxation.String(),
+			rateCell(point.EffectiveRate), rateCell(point.MarginalRate),
+			point.Net.String(), point.MonthlyNet.String(), point.EmployerCost.String())
+	}
+	return []*Table{t}
+}
+
+// Chart 转为折线图：上方为实际税率和边际税率，下方为全年税后收入和用工成本
+func (p *SweepResult) Chart() *chart.Chart {
+	name := SweepParamName(p.Param)
+	c := &chart.Chart{
+		Title:  fmt.Sprintf("按%s扫描", name),
+		XLabel: name,
+	}
+	effective := &chart.Series{Name: "实际税率"}
+	marginal := &chart.Series{Name: "边际税率"}
+	net := &chart.Series{Name: "全年税后"}
+	cost := &chart.Series{Name: "用工成本"}
+	for _, point := range p.Points {
+		c.X = append(c.X, point.Value)
+		effective.Values = append(effective.Values, point.EffectiveRate)
+		marginal.Values = append(marginal.Values, point.MarginalRate)
+		net.Values = append(net.Values, point.Net.Yuan())
+		cost.Values = append(cost.Values, point.EmployerCost.Yuan())
+	}
+	c.Panels = []*chart.Panel{
+		{YLabel: "税率(%)", Series: []*chart.Series{effective, marginal}},
+		{YLabel: "金额(元)", Series: []*chart.Series{net, cost}},
+	}
+	return c
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import "testing"
+
+// testMonthTaxRates 按月换算后的综合所得税率表
+var testMonthTaxRates = []YearTaxRate{
+	{SalaryMin: NewMoney(0), SalaryMax: NewMoney(3000), Rate: 3, DeductedAmount: NewMoney(0)},
+	{SalaryMin: NewMoney(3000), SalaryMax: NewMoney(12000), Rate: 10, DeductedAmount: NewMoney(210)},
+	{SalaryMin: NewMoney(12000), SalaryMax: NewMoney(25000), Rate: 20, DeductedAmount: NewMoney(1410)},
+	{SalaryMin: NewMoney(25000), SalaryMax: NewMoney(35000), Rate: 25, DeductedAmount: NewMoney(2660)},
+	{SalaryMin: NewMoney(35000), SalaryMax: NewMoney(55000), Rate: 30, DeductedAmount: NewMoney(4410)},
+	{SalaryMin: NewMoney(55000), SalaryMax: NewMoney(80000), Rate: 35, DeductedAmount: NewMoney(7160)},
+	{SalaryMin: NewMoney(80000), SalaryMax: NewMoney(0), Rate: 45, DeductedAmount: NewMoney(15160)},
+}
+
+func TestSweepBonusMarginalRate(t *testing.T) {
+	h := &TaxesHandler{YearTaxBase: YearTaxBase{YearTaxRates: testYearTaxRates, MonthTaxRates: testMonthTaxRates}}
+	salaries := &Salaries{
+		Year: 2024,
+		For:  true,
+		MonthlySalaries: []MonthlySalary{
+			{SalaryBase: SalaryBase{Threshold: NewMoney(5000), Salary: NewMoney(30000)}},
+		},
+	}
+
+	result, err := h.Sweep(salaries, SweepBonus, SweepRange{From: 35000, To: 37000, Step: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 奖金单独计税，与月薪无关：36000 元处多发 100 元奖金多交 2320 元个税
+	for i, want := range []float64{3, 2320, 10} {
+		if got := result.Points[i].MarginalRate; got != want {
+			t.Errorf("奖金 %v 时边际税率 %v%%, 应为 %v%%", result.Points[i].Value, got, want)
+		}
+	}
+}
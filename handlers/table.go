@@ -0,0 +1,55 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"fmt"
+)
+
+// Table 表格形式的结果，用于输出 csv、markdown 等格式
+type Table struct {
+	Title   string     `yaml:"title" json:"title"`
+	Headers []string   `yaml:"headers" json:"headers"`
+	Rows    [][]string `yaml:"rows" json:"rows"`
+	// 合计行，为空表示没有合计
+	Totals []string `yaml:"totals,omitempty" json:"totals,omitempty"`
+}
+
+// Tabular 可以转为表格输出的结果
+type Tabular interface {
+	Tables() []*Table
+}
+
+// AddRow 追加一行
+func (p *Table) AddRow(cells ...string) {
+	p.Rows = append(p.Rows, cells)
+}
+
+// rateCell 比例单元格，单位为百分比
+func rateCell(rate float64) string {
+	return fmt.Sprintf("%.2f", rate)
+}
+
+// moneyCells 金额单元格
+func moneyCells(amounts ...Money) []string {
+	cells := make([]string, len(amounts))
+	for i, amount := range amounts {
+		cells[i] = amount.String()
+	}
+	return cells
+}
@@ -19,20 +19,51 @@ package handlers
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/go-trellis/config"
 )
 
-// TaxesHandler handler 对象
+// TaxesHandler handler 对象，加载后只读，累计状态保存在 TaxContext 中
 type TaxesHandler struct {
 	AccumulationFundHandler `yaml:",inline" json:",inline"`
 	InsurancesHandler       `yaml:",inline" json:",inline"`
 
 	YearTaxBase `yaml:",inline" json:",inline"`
 
-	totalSalaries    float64
-	totalTaxSalaries float64
-	totalTaxation    float64
+	PolicyRegistry `yaml:",inline" json:",inline"`
+}
+
+// TaxContext 单次计算的累计状态，同一个 TaxesHandler 可以被多次、并发地使用
+type TaxContext struct {
+	// 当前扣缴义务人的累计台账，更换任职受雇单位后重新累计
+	Employer string    `yaml:"employer" json:"employer"`
+	Ledger   TaxLedger `yaml:"ledger" json:"ledger"`
+
+	// 全年各扣缴义务人的合计，用于年度汇算
+	Annual TaxLedger `yaml:"annual" json:"annual"`
+}
+
+// TaxLedger 累计预扣法的年度累计台账
+type TaxLedger struct {
+	Months                     int   `yaml:"months" json:"months"`                                             // 累计减除费用的月份数
+	Income                     Money `yaml:"income" json:"income"`                                             // 累计收入
+	Deduction                  Money `yaml:"deduction" json:"deduction"`                                       // 累计减除费用
+	SpecialDeduction           Money `yaml:"special_deduction" json:"special_deduction"`                       // 累计专项扣除（三险一金）
+	SpecialAdditionalDeduction Money `yaml:"special_additional_deduction" json:"special_additional_deduction"` // 累计专项附加扣除
+	TaxableIncome              Money `yaml:"taxable_income" json:"taxable_income"`                             // 累计应纳税所得额
+	TaxPayable                 Money `yaml:"tax_payable" json:"tax_payable"`                                   // 累计应纳税额
+	WithheldTax                Money `yaml:"withheld_tax" json:"withheld_tax"`                                 // 累计已预扣预缴税额
+	UnrefundedTax              Money `yaml:"unrefunded_tax" json:"unrefunded_tax"`                             // 多预扣部分，预扣时不退，年度汇算时处理
+	RestSalary                 Money `yaml:"rest_salary" json:"rest_salary"`                                   // 累计税后收入
+}
+
+// NewTaxContext 生成新的计算上下文
+func NewTaxContext() *TaxContext {
+	return &TaxContext{}
 }
 
 // NewTaxesHandler 生成handler对象
@@ -46,117 +77,528 @@ func NewTaxesHandler(file string) (*TaxesHandler, error) {
 
 // Salaries 薪资配置参数
 type Salaries struct {
+	// 用最后一条月工资补足到12月，更复杂的情况请使用 Schedule
 	For bool `yaml:"for" json:"for"`
+	// 计算年度，用于选择各月适用的政策以及专项附加扣除标准，为 0 时按月工资中的年月，都未设置时使用最新政策
+	Year int `yaml:"year" json:"year"`
+	// 年度首次取得工资薪金，第一个任职受雇单位的减除费用从1月起累计
+	FirstEmployment bool `yaml:"first_employment" json:"first_employment"`
+
+	PersonalInfo `yaml:",inline" json:",inline"`
+
+	MonthlySalaries []MonthlySalary `yaml:"monthly_salaries" json:"monthly_salaries"`
+	// 月工资计划，计算前展开为逐月的月工资，与 MonthlySalaries 二选一
+	Schedule *Schedule `yaml:"schedule" json:"schedule"`
+
+	// 全年一次性奖金
+	Bonus *Bonus `yaml:"bonus" json:"bonus"`
+
+	// 工资薪金以外的综合所得，用于年度汇算
+	OtherIncomes *ComprehensiveIncome `yaml:"other_incomes" json:"other_incomes"`
+}
+
+// Validate 校验薪资配置，各月的年月和顺序在计算时校验
+func (p *Salaries) Validate() error {
+	if err := p.PersonalInfo.Validate(); err != nil {
+		return err
+	}
+	for _, s := range p.MonthlySalaries {
+		if err := s.Validate(); err != nil {
+			return &MonthError{Month: s.Month, Err: err}
+		}
+	}
+	if p.Schedule != nil {
+		if err := p.Schedule.Template.Validate(); err != nil {
+			return fmt.Errorf("月工资计划: %v", err)
+		}
+		for _, rule := range p.Schedule.Rules {
+			if err := rule.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if p.Bonus != nil {
+		if err := p.Bonus.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MonthError 某月的月工资校验或计算失败
+type MonthError struct {
+	Month YearMonth
+	Err   error
+}
 
-	PersonalInfo PersonalInfo `yaml:",inline" json:",inline"`
+func (p *MonthError) Error() string {
+	return fmt.Sprintf("月工资 %s: %v", p.Month, p.Err)
+}
 
-	MonthlySalaries []SalaryBase `yaml:"monthly_salaries" json:"monthly_salaries"`
+// Unwrap 返回原始的错误
+func (p *MonthError) Unwrap() error {
+	return p.Err
+}
+
+// MonthlySalary 某月的薪水信息
+type MonthlySalary struct {
+	// 所属年月，格式 2006-01，也可以填发放日期 2006-01-02，不填则为上一条的次月，第一条默认为1月
+	Month YearMonth `yaml:"month" json:"month"`
+	// 任职受雇单位（扣缴义务人），更换单位后按新单位重新累计预扣，不填则沿用之前的单位
+	Employer string `yaml:"employer" json:"employer"`
+
+	SalaryBase `yaml:",inline" json:",inline"`
+
+	// 户口类型、养老类型的变更，从该月起生效，不填则沿用之前的设置
+	Residence *ResidenceType `yaml:"residence" json:"residence"`
+	Endowment *EndowmentType `yaml:"endowment" json:"endowment"`
+
+	// 月工资计划展开时，该月执行的调整
+	Changes []string `yaml:"changes,omitempty" json:"changes,omitempty"`
+
+	// For 为 true 时用最后一条补足的月份
+	projected bool
 }
 
 // MonthlyTaxes 返回的对象
 type MonthlyTaxes struct {
 	Taxes []*MonthlyTax `yaml:"taxes" json:"taxes"`
+	// 有补足的月份时，预测补足月份中税后收入下降的月份
+	Forecast *NetPayForecast `yaml:"forecast,omitempty" json:"forecast,omitempty"`
 }
 
 // MonthlyTax 月薪对象
 type MonthlyTax struct {
-	Month int `yaml:"month" json:"month"`
+	Year     int    `yaml:"year" json:"year"`
+	Month    int    `yaml:"month" json:"month"`
+	Employer string `yaml:"employer" json:"employer"`
+
+	// 当月适用的户口类型、养老类型
+	Residence ResidenceType `yaml:"residence" json:"residence"`
+	Endowment EndowmentType `yaml:"endowment" json:"endowment"`
 
 	SalaryBase `yaml:",inline" json:",inline"`
+	// 月工资计划展开时，该月执行的调整
+	Changes []string `yaml:"changes,omitempty" json:"changes,omitempty"`
+	// 按最后一条月工资补足的月份
+	Projected bool `yaml:"projected,omitempty" json:"projected,omitempty"`
 
 	InsurancesResult       *CalcInsurancesAmount `yaml:"insurances_result" json:"insurances_result"`
 	AccumulationFundResult *CalcAccumulationFund `yaml:"accumulation_fund_result" json:"accumulation_fund_result"`
 
-	Insurances       float64 `yaml:"insurances" json:"insurances"`
-	AccumulationFund float64 `yaml:"accumulation_fund" json:"accumulation_fund"`
+	Insurances       Money `yaml:"insurances" json:"insurances"`
+	AccumulationFund Money `yaml:"accumulation_fund" json:"accumulation_fund"`
+
+	// 当月按专项附加扣除信息计算出的扣除金额
+	SpecialAdditionalDeduction Money `yaml:"special_additional_deduction" json:"special_additional_deduction"`
+
+	// 当月发放的全年一次性奖金
+	Bonus         Money       `yaml:"bonus" json:"bonus"`
+	BonusMethod   BonusMethod `yaml:"bonus_method" json:"bonus_method"`
+	BonusTaxation Money       `yaml:"bonus_taxation" json:"bonus_taxation"`
+
+	Taxation        Money `yaml:"taxation" json:"taxation"`
+	RestSalary      Money `yaml:"rest_salary" json:"rest_salary"`
+	HistoryTaxation Money `yaml:"history_taxation" json:"history_taxation"`
+	HistorySalary   Money `yaml:"history_salary" json:"history_salary"`
+
+	// 累计应纳税所得额适用的税率，即此后多发工资的边际税率
+	TaxRate float64 `yaml:"tax_rate" json:"tax_rate"`
+	// 本月累计应纳税所得额跨越的税率级距，一次跨越多档时按级距从低到高各记一条
+	BracketCrossings BracketCrossings `yaml:"bracket_crossings,omitempty" json:"bracket_crossings,omitempty"`
+
+	// 本月计算完成后，当前扣缴义务人的累计台账
+	Ledger TaxLedger `yaml:"ledger" json:"ledger"`
+	// 本月计算完成后的全年合计
+	Annual TaxLedger `yaml:"annual" json:"annual"`
 
-	Taxation        float64 `yaml:"taxation" json:"taxation"`
-	RestSalary      float64 `yaml:"rest_salary" json:"rest_salary"`
-	HistoryTaxation float64 `yaml:"history_taxation" json:"history_taxation"`
-	HistorySalary   float64 `yaml:"history_salary" json:"history_salary"`
+	// 计算过程
+	Explanation *Step `yaml:"-" json:"-"`
 }
 
 // Calc 计算月薪剩余以及个税情况
-func (p *TaxesHandler) Calc(salaries *Salaries) (t *MonthlyTaxes, err error) {
+func (p *TaxesHandler) Calc(salaries *Salaries) (*MonthlyTaxes, error) {
+	return p.CalcWithContext(NewTaxContext(), salaries)
+}
+
+// CalcWithContext 在给定的计算上下文中计算月薪剩余以及个税情况
+func (p *TaxesHandler) CalcWithContext(ctx *TaxContext, salaries *Salaries) (*MonthlyTaxes, error) {
+	salaries, err := salaries.Expand()
+	if err != nil {
+		return nil, err
+	}
+
+	monthlySalaries, err := salaries.months()
+	if err != nil {
+		return nil, err
+	}
+
+	bonus := salaries.Bonus
+	if bonus != nil && bonus.Amount > 0 && !salaries.hasMonth(monthlySalaries, bonus.Month) {
+		return nil, fmt.Errorf("奖金发放月份 %d 月没有月工资", bonus.Month)
+	}
+
 	taxes := &MonthlyTaxes{}
-	info := &PersonalInfo{}
-	lastMonth := 0
-	for i, s := range salaries.MonthlySalaries {
-		lastMonth = i + 1
+	info := salaries.PersonalInfo
+	agents := 0
+	for _, s := range monthlySalaries {
+		month := s.Month.Month
+		h, err := p.Handler(salaries.PersonalInfo.Jurisdiction, salaries.policyMonth(s.Month))
+		if err != nil {
+			return nil, &MonthError{Month: s.Month, Err: err}
+		}
+
+		if s.Residence != nil {
+			info.Residence = *s.Residence
+		}
+		if s.Endowment != nil {
+			info.Endowment = *s.Endowment
+		}
+		info.SalaryBase = s.SalaryBase
+
+		// 更换扣缴义务人后，按新单位重新累计
+		changed := agents > 0 && s.Employer != ctx.Employer
+		if agents == 0 || changed {
+			agents++
+			ctx.Employer = s.Employer
+			ctx.Ledger = TaxLedger{}
+		}
+
 		iMonthTax := &MonthlyTax{
-			Month:      lastMonth,
-			SalaryBase: s,
+			Year:       s.Month.Year,
+			Month:      month,
+			Employer:   s.Employer,
+			Residence:  info.Residence,
+			Endowment:  info.Endowment,
+			SalaryBase: s.SalaryBase,
+			Changes:    s.Changes,
+			Projected:  s.projected,
 		}
 
-		info.SalaryBase = s
+		if salaries.PersonalInfo.SpecialDeductions != nil {
+			standard, err := h.SpecialDeductionStandard(salaries.year())
+			if err != nil {
+				return nil, &MonthError{Month: s.Month, Err: err}
+			}
+			iMonthTax.SpecialAdditionalDeduction, err = salaries.PersonalInfo.SpecialDeductions.Monthly(standard)
+			if err != nil {
+				return nil, &MonthError{Month: s.Month, Err: err}
+			}
+		}
 
-		iMonthTax.AccumulationFundResult, err = p.AccumulationFundHandler.Calc(info)
+		iMonthTax.AccumulationFundResult, err = h.AccumulationFundHandler.Calc(&info)
 		if err != nil {
-			return nil, err
+			return nil, &MonthError{Month: s.Month, Err: err}
 		}
 
-		iMonthTax.InsurancesResult, err = p.InsurancesHandler.Calc(info)
+		iMonthTax.InsurancesResult, err = h.InsurancesHandler.Calc(&info)
 		if err != nil {
-			return nil, err
+			return nil, &MonthError{Month: s.Month, Err: err}
 		}
 
-		iMonthTax.Insurances = iMonthTax.InsurancesResult.Private.EndowmentAmount +
-			iMonthTax.InsurancesResult.Private.MedicalAmount +
-			iMonthTax.InsurancesResult.Private.UnemploymentAmount +
-			iMonthTax.InsurancesResult.Private.EmploymentInjuryAmount +
-			iMonthTax.InsurancesResult.Private.BirthAmount +
-			iMonthTax.InsurancesResult.Private.SeriousMedicalAmount
+		iMonthTax.Insurances = iMonthTax.InsurancesResult.PrivateTotalAmount
 
 		iMonthTax.AccumulationFund = iMonthTax.AccumulationFundResult.PrivateFund
 
-		p.getMonthTax(iMonthTax)
+		if bonus != nil && bonus.Amount > 0 && bonus.Month == month {
+			iMonthTax.Bonus = bonus.Amount
+			iMonthTax.BonusMethod = bonus.Method
+			if iMonthTax.BonusMethod == "" {
+				iMonthTax.BonusMethod = BonusSeparate
+			}
+		}
+
+		// 减除费用按在本单位的任职受雇月份数累计，年度首次取得工资薪金的从1月起累计
+		months := ctx.Ledger.Months + 1
+		if salaries.FirstEmployment && agents == 1 {
+			months = month
+		}
+
+		h.getMonthTax(ctx, iMonthTax, months)
+		if changed {
+			iMonthTax.Explanation.Note = fmt.Sprintf("更换任职受雇单位为 %s，重新累计预扣", s.Employer)
+		}
 
 		taxes.Taxes = append(taxes.Taxes, iMonthTax)
 	}
+	taxes.Forecast = taxes.forecast()
 
-	if salaries.For {
-		for i := lastMonth; i < 12; i++ {
-			monthlyTax := taxes.Taxes[lastMonth-1]
+	return taxes, nil
+}
 
-			monthlyTaxNext := *monthlyTax
-			monthlyTaxNext.Month = i + 1
-			p.getMonthTax(&monthlyTaxNext)
-			taxes.Taxes = append(taxes.Taxes, &monthlyTaxNext)
+// months 确定每条月工资的所属年月，For 为 true 时用最后一条补足到当年12月
+func (p *Salaries) months() ([]MonthlySalary, error) {
+	year := p.year()
+
+	var months []MonthlySalary
+	for _, s := range p.MonthlySalaries {
+		if s.Month.IsZero() {
+			s.Month = YearMonth{Year: year, Month: 1}
+			if len(months) > 0 {
+				s.Month = months[len(months)-1].Month.AddMonths(1)
+			}
+		}
+		if s.Month.Year != year {
+			return nil, &MonthError{Month: s.Month, Err: fmt.Errorf("不在计算年度 %d 内", year)}
+		}
+		if len(months) > 0 {
+			prev := months[len(months)-1]
+			if !prev.Month.Before(s.Month) {
+				return nil, &MonthError{Month: s.Month, Err: fmt.Errorf("需按月份先后排列，不能在 %s 之后", prev.Month)}
+			}
+			if s.Employer == "" {
+				s.Employer = prev.Employer
+			}
 		}
+		months = append(months, s)
 	}
 
-	return taxes, nil
+	if p.For && len(months) > 0 {
+		last := months[len(months)-1]
+		last.Residence, last.Endowment = nil, nil
+		last.projected = true
+		for last.Month.Month < 12 {
+			last.Month = last.Month.AddMonths(1)
+			months = append(months, last)
+		}
+	}
+	return months, nil
+}
+
+// hasMonth 是否有该月的月工资
+func (p *Salaries) hasMonth(months []MonthlySalary, month int) bool {
+	for _, s := range months {
+		if s.Month.Month == month {
+			return true
+		}
+	}
+	return false
+}
+
+// policyMonth 选择政策使用的年月，未设置年度时返回零值，表示使用最新政策
+func (p *Salaries) policyMonth(month YearMonth) YearMonth {
+	if month.Year == 0 {
+		return YearMonth{}
+	}
+	return month
+}
+
+// yearMonth 计算年度中第 month 个月对应的年月，未设置年度时返回零值，表示使用最新政策
+func (p *Salaries) yearMonth(month int) YearMonth {
+	return p.policyMonth(YearMonth{Year: p.year(), Month: month})
+}
+
+// year 计算年度，未设置时使用月工资中第一个填写的年份
+func (p *Salaries) year() int {
+	if p.Year != 0 {
+		return p.Year
+	}
+	if p.Schedule != nil && !p.Schedule.From.IsZero() {
+		return p.Schedule.From.Year
+	}
+	for _, s := range p.MonthlySalaries {
+		if !s.Month.IsZero() {
+			return s.Month.Year
+		}
+	}
+	return 0
+}
+
+// getMonthTax 按累计预扣法计算当月个税，months 为截至本月累计减除费用的月份数
+func (p *TaxesHandler) getMonthTax(ctx *TaxContext, monthlyTax *MonthlyTax, months int) {
+	income := monthlyTax.Salary + monthlyTax.SubsidyAmount + monthlyTax.ExtraAmount
+	if monthlyTax.BonusMethod == BonusMerged {
+		income += monthlyTax.Bonus
+	}
+	specialDeduction := monthlyTax.Insurances + monthlyTax.AccumulationFund
+
+	additionalDeduction := monthlyTax.DeductibleAmount + monthlyTax.SpecialAdditionalDeduction
+	// 减除费用按月累计，不论当月是否达到起征点
+	added := months - ctx.Ledger.Months
+	deduction := monthlyTax.Threshold.Mul(int64(added))
+
+	prev := ctx.Ledger
+	ledger := &ctx.Ledger
+	ledger.Months = months
+	ledger.Income += income
+	ledger.Deduction += deduction
+	ledger.SpecialDeduction += specialDeduction
+	ledger.SpecialAdditionalDeduction += additionalDeduction
+
+	ledger.TaxableIncome = ledger.Income - ledger.Deduction -
+		ledger.SpecialDeduction - ledger.SpecialAdditionalDeduction
+	if ledger.TaxableIncome < 0 {
+		ledger.TaxableIncome = 0
+	}
+	ledger.TaxPayable = p.CalcYearTax(ledger.TaxableIncome)
+
+	// 上月还没有应纳税所得额时，按最低一档比较
+	prevRate, prevOk := p.FindYearTaxRate(prev.TaxableIncome)
+	if !prevOk && len(p.YearTaxRates) > 0 {
+		prevRate = p.YearTaxRates[0]
+	}
+	rate, ok := p.FindYearTaxRate(ledger.TaxableIncome)
+	if ok {
+		monthlyTax.TaxRate = rate.Rate
+	}
+	if ok {
+		from := prevRate.Rate
+		for _, r := range p.YearTaxRates {
+			if r.SalaryMin <= prevRate.SalaryMin || r.SalaryMin > rate.SalaryMin {
+				continue
+			}
+			monthlyTax.BracketCrossings = append(monthlyTax.BracketCrossings,
+				&BracketCrossing{Boundary: r.SalaryMin, From: from, To: r.Rate})
+			from = r.Rate
+		}
+	}
+
+	// 累计应纳税额小于已预扣税额时，本月不扣税，也不退税，差额留待年度汇算
+	tax := ledger.TaxPayable - ledger.WithheldTax
+	if tax < 0 {
+		ledger.UnrefundedTax = -tax
+		tax = 0
+	} else {
+		ledger.UnrefundedTax = 0
+	}
+	ledger.WithheldTax += tax
+
+	monthlyTax.Taxation = tax
+	monthlyTax.RestSalary = income - specialDeduction - tax
+	if monthlyTax.BonusMethod == BonusSeparate {
+		monthlyTax.BonusTaxation = p.CalcBonusTax(monthlyTax.Bonus)
+		monthlyTax.RestSalary += monthlyTax.Bonus - monthlyTax.BonusTaxation
+	}
+	ledger.RestSalary += monthlyTax.RestSalary
+
+	annual := &ctx.Annual
+	annual.Months += added
+	annual.Income += income
+	annual.Deduction += deduction
+	annual.SpecialDeduction += specialDeduction
+	annual.SpecialAdditionalDeduction += additionalDeduction
+	annual.WithheldTax += tax
+	annual.RestSalary += monthlyTax.RestSalary
+
+	monthlyTax.HistorySalary = annual.RestSalary
+	monthlyTax.HistoryTaxation = annual.WithheldTax
+	monthlyTax.Ledger = *ledger
+	monthlyTax.Annual = *annual
+
+	monthlyTax.Explanation = p.explainMonthTax(monthlyTax, &prev, income, added)
 }
 
-func (p *TaxesHandler) getMonthTax(monthlyTax *MonthlyTax) {
+// explainMonthTax 当月个税的计算过程，prev 为上月的累计台账
+func (p *TaxesHandler) explainMonthTax(monthlyTax *MonthlyTax, prev *TaxLedger, income Money, added int) *Step {
+	ledger := &monthlyTax.Ledger
+
+	incomeStep := NewStep("本月收入", "月薪 + 补贴 + 额外工资").
+		Money("月薪", monthlyTax.Salary).Money("补贴", monthlyTax.SubsidyAmount).
+		Money("额外工资", monthlyTax.ExtraAmount)
+	if monthlyTax.BonusMethod == BonusMerged {
+		incomeStep.Formula += " + 奖金"
+		incomeStep.Money("奖金", monthlyTax.Bonus).Note = "奖金并入综合所得"
+	}
+	incomeStep.Set(income)
+
+	insurances := NewStep("社保个人缴纳", "").Set(monthlyTax.Insurances)
+	if monthlyTax.InsurancesResult != nil {
+		insurances.Add(monthlyTax.InsurancesResult.Explanation.Steps...)
+	}
+	fund := NewStep("公积金个人缴纳", "").Set(monthlyTax.AccumulationFund)
+	if monthlyTax.AccumulationFundResult != nil {
+		fund.Add(monthlyTax.AccumulationFundResult.Explanation.Steps...)
+	}
+
+	taxRate, ok := p.FindYearTaxRate(ledger.TaxableIncome)
 
-	monthlyTax.RestSalary = Decimal2(monthlyTax.Salary + monthlyTax.SubsidyAmount -
-		monthlyTax.Insurances - monthlyTax.AccumulationFund)
-	taxSalary := monthlyTax.RestSalary - monthlyTax.Threshold - monthlyTax.DeductibleAmount
-	if taxSalary > 0 {
-		p.totalTaxSalaries += taxSalary
+	withheld := NewStep("本月个税", "累计应纳税额 − 累计已预扣税额").
+		Money("累计应纳税额", ledger.TaxPayable).Money("累计已预扣税额", prev.WithheldTax).
+		Set(monthlyTax.Taxation)
+	if ledger.UnrefundedTax > 0 {
+		withheld.Note = fmt.Sprintf("已多预扣 %s，本月不扣税，年度汇算时退还", ledger.UnrefundedTax)
 	}
-	p.totalSalaries += monthlyTax.RestSalary
 
-	// 小于起征点，那么税收为0
-	if monthlyTax.RestSalary-monthlyTax.DeductibleAmount <= monthlyTax.Threshold {
-		return
+	step := NewStep(fmt.Sprintf("%d月", monthlyTax.Month), "").Add(
+		incomeStep,
+		insurances,
+		fund,
+		NewStep("累计收入", "上月累计收入 + 本月收入").
+			Money("上月累计收入", prev.Income).Money("本月收入", income).Set(ledger.Income),
+		NewStep("累计减除费用", "上月累计减除费用 + 起征线 × 新增月数").
+			Money("上月累计减除费用", prev.Deduction).Money("起征线", monthlyTax.Threshold).
+			Input("新增月数", fmt.Sprintf("%d", added)).Set(ledger.Deduction),
+		NewStep("累计专项扣除", "上月累计专项扣除 + 社保个人缴纳 + 公积金个人缴纳").
+			Money("上月累计专项扣除", prev.SpecialDeduction).
+			Money("社保个人缴纳", monthlyTax.Insurances).Money("公积金个人缴纳", monthlyTax.AccumulationFund).
+			Set(ledger.SpecialDeduction),
+		NewStep("累计专项附加扣除", "上月累计专项附加扣除 + 其他可抵扣金额 + 专项附加扣除").
+			Money("上月累计专项附加扣除", prev.SpecialAdditionalDeduction).
+			Money("其他可抵扣金额", monthlyTax.DeductibleAmount).
+			Money("专项附加扣除", monthlyTax.SpecialAdditionalDeduction).
+			Set(ledger.SpecialAdditionalDeduction),
+		NewStep("累计应纳税所得额", "累计收入 − 累计减除费用 − 累计专项扣除 − 累计专项附加扣除").
+			Money("累计收入", ledger.Income).Money("累计减除费用", ledger.Deduction).
+			Money("累计专项扣除", ledger.SpecialDeduction).
+			Money("累计专项附加扣除", ledger.SpecialAdditionalDeduction).
+			Set(ledger.TaxableIncome),
+		explainTax("累计应纳税额", "累计应纳税所得额", ledger.TaxableIncome, taxRate, ok, 1,
+			p.rounding(), ledger.TaxPayable),
+		withheld,
+	)
+
+	rest := NewStep("剩余工资", "本月收入 − 社保个人缴纳 − 公积金个人缴纳 − 本月个税").
+		Money("本月收入", income).Money("社保个人缴纳", monthlyTax.Insurances).
+		Money("公积金个人缴纳", monthlyTax.AccumulationFund).Money("本月个税", monthlyTax.Taxation)
+	if monthlyTax.BonusMethod == BonusSeparate {
+		bonusRate, ok := findTaxRate(p.MonthTaxRates, monthlyTax.Bonus, 12)
+		step.Add(explainTax("奖金个税", "奖金", monthlyTax.Bonus, bonusRate, ok, 12,
+			p.rounding(), monthlyTax.BonusTaxation))
+		rest.Formula += " + 奖金 − 奖金个税"
+		rest.Money("奖金", monthlyTax.Bonus).Money("奖金个税", monthlyTax.BonusTaxation)
 	}
+	step.Add(rest.Set(monthlyTax.RestSalary))
+	step.Result = fmt.Sprintf("个税 %s, 剩余工资 %s", monthlyTax.Taxation+monthlyTax.BonusTaxation,
+		monthlyTax.RestSalary)
+	return step
+}
 
-	for _, taxRate := range p.YearTaxRates {
-		if p.totalTaxSalaries <= taxRate.SalaryMin ||
-			(p.totalTaxSalaries > taxRate.SalaryMax && taxRate.SalaryMax != 0) {
+// Explain 全年各月的计算过程
+func (p *MonthlyTaxes) Explain() *Step {
+	step := NewStep("个税（累计预扣法）", "")
+	for _, t := range p.Taxes {
+		step.Add(t.Explanation)
+	}
+	return step
+}
+
+// CalcYearTax 按年度税率表计算累计应纳税所得额对应的应纳税额
+func (p *YearTaxBase) CalcYearTax(taxableIncome Money) Money {
+	taxRate, ok := p.FindYearTaxRate(taxableIncome)
+	if !ok {
+		return 0
+	}
+	return taxableIncome.MulRate(taxRate.Rate, p.rounding()) - taxRate.DeductedAmount
+}
+
+// FindYearTaxRate 查找应纳税所得额所在的税率档
+func (p *YearTaxBase) FindYearTaxRate(taxableIncome Money) (YearTaxRate, bool) {
+	return findTaxRate(p.YearTaxRates, taxableIncome, 1)
+}
+
+// findTaxRate 查找 taxableIncome/months 所在的税率档，为避免除法的舍入，改为比较档位上下限的 months 倍
+func findTaxRate(rates []YearTaxRate, taxableIncome Money, months int64) (YearTaxRate, bool) {
+	if taxableIncome <= 0 {
+		return YearTaxRate{}, false
+	}
+	for _, taxRate := range rates {
+		if taxableIncome <= taxRate.SalaryMin.Mul(months) ||
+			(taxableIncome > taxRate.SalaryMax.Mul(months) && taxRate.SalaryMax != 0) {
 			continue
 		}
-		tax := Decimal2((p.totalTaxSalaries*taxRate.Rate)/100.0 - taxRate.DeductedAmount - p.totalTaxation)
-		p.totalTaxation += tax
-
-		monthlyTax.Taxation = tax
-		monthlyTax.RestSalary = Decimal2(monthlyTax.RestSalary - tax)
-		monthlyTax.HistorySalary = Decimal2(p.totalSalaries)
-		monthlyTax.HistoryTaxation = Decimal2(p.totalTaxation)
-		return
+		return taxRate, true
 	}
+	return YearTaxRate{}, false
 }
 
 const (
@@ -165,8 +607,137 @@ const (
 
 // Print 打印信息
 func (p *MonthlyTaxes) Print() {
+	p.Fprint(os.Stdout)
+}
+
+// Fprint 输出信息到 w
+func (p *MonthlyTaxes) Fprint(w io.Writer) {
+	for i, t := range p.Taxes {
+		line := fmt.Sprintf(printTaxInfor, t.Month, t.Salary, t.SubsidyAmount,
+			t.Insurances, t.AccumulationFund, t.Taxation, t.RestSalary)
+		if t.Employer != "" && (i == 0 || t.Employer != p.Taxes[i-1].Employer) {
+			line += fmt.Sprintf(", 任职单位: %s", t.Employer)
+		}
+		if t.ExtraAmount > 0 {
+			line += fmt.Sprintf(", 额外工资: %.2f", t.ExtraAmount)
+		}
+		if t.Bonus > 0 {
+			line += fmt.Sprintf(", 奖金: %.2f(%s), 奖金个税: %.2f", t.Bonus, BonusMethodName(t.BonusMethod), t.BonusTaxation)
+		}
+		if t.Ledger.UnrefundedTax > 0 {
+			line += fmt.Sprintf(", 多预扣税额(年度汇算退还): %.2f", t.Ledger.UnrefundedTax)
+		}
+		if len(t.Changes) > 0 {
+			line += fmt.Sprintf(", 调整: %s", strings.Join(t.Changes, "；"))
+		}
+		if len(t.BracketCrossings) > 0 {
+			line += ", " + t.BracketCrossings.String()
+		}
+		fmt.Fprintln(w, line)
+	}
+	if p.Forecast != nil {
+		p.Forecast.fprint(w)
+	}
+}
+
+// Tables 转为表格
+func (p *MonthlyTaxes) Tables() []*Table {
+	t := &Table{
+		Title: "月工资",
+		Headers: []string{"月份", "任职单位", "收入", "补贴", "额外工资", "社保缴纳", "公积金缴纳",
+			"个税缴纳", "奖金", "奖金个税", "剩余工资", "适用税率", "跨越级距"},
+	}
+	var totals [9]Money
+	for _, tax := range p.Taxes {
+		amounts := [9]Money{tax.Salary, tax.SubsidyAmount, tax.ExtraAmount, tax.Insurances,
+			tax.AccumulationFund, tax.Taxation, tax.Bonus, tax.BonusTaxation, tax.RestSalary}
+		for i, amount := range amounts {
+			totals[i] += amount
+		}
+		row := append([]string{monthCell(tax.Year, tax.Month), tax.Employer}, moneyCells(amounts[:]...)...)
+		t.AddRow(append(row, rateCell(tax.TaxRate), tax.BracketCrossings.String())...)
+	}
+	t.Totals = append(append([]string{"合计", ""}, moneyCells(totals[:]...)...), "", "")
+	if p.Forecast != nil {
+		return []*Table{t, p.Forecast.table()}
+	}
+	return []*Table{t}
+}
+
+// monthCell 月份，未设置年度时只有月
+func monthCell(year, month int) string {
+	if year == 0 {
+		return strconv.Itoa(month)
+	}
+	return YearMonth{Year: year, Month: month}.String()
+}
+
+// Totals 金额合计
+type Totals struct {
+	Income                  Money `yaml:"income" json:"income"`                                       // 税前收入，含奖金
+	Insurances              Money `yaml:"insurances" json:"insurances"`                               // 个人社保
+	AccumulationFund        Money `yaml:"accumulation_fund" json:"accumulation_fund"`                 // 个人公积金
+	Taxation                Money `yaml:"taxation" json:"taxation"`                                   // 个税，含奖金个税
+	RestSalary              Money `yaml:"rest_salary" json:"rest_salary"`                             // 税后收入
+	CompanyInsurances       Money `yaml:"company_insurances" json:"company_insurances"`               // 单位社保
+	CompanyAccumulationFund Money `yaml:"company_accumulation_fund" json:"company_accumulation_fund"` // 单位公积金
+	CompanyCost             Money `yaml:"company_cost" json:"company_cost"`                           // 用工成本
+}
+
+// Add 累加另一个合计
+func (p *Totals) Add(o Totals) {
+	p.Income += o.Income
+	p.Insurances += o.Insurances
+	p.AccumulationFund += o.AccumulationFund
+	p.Taxation += o.Taxation
+	p.RestSalary += o.RestSalary
+	p.CompanyInsurances += o.CompanyInsurances
+	p.CompanyAccumulationFund += o.CompanyAccumulationFund
+	p.CompanyCost += o.CompanyCost
+}
+
+// Totals 当月的金额合计
+func (p *MonthlyTax) Totals() Totals {
+	t := Totals{
+		Income:           p.Salary + p.SubsidyAmount + p.ExtraAmount + p.Bonus,
+		Insurances:       p.Insurances,
+		AccumulationFund: p.AccumulationFund,
+		Taxation:         p.Taxation + p.BonusTaxation,
+		RestSalary:       p.RestSalary,
+	}
+	if p.InsurancesResult != nil {
+		t.CompanyInsurances = p.InsurancesResult.CompanyTotalAmount
+	}
+	if p.AccumulationFundResult != nil {
+		t.CompanyAccumulationFund = p.AccumulationFundResult.CompanyFund
+	}
+	t.CompanyCost = t.Income + t.CompanyInsurances + t.CompanyAccumulationFund
+	return t
+}
+
+// Totals 全年的金额合计
+func (p *MonthlyTaxes) Totals() Totals {
+	var totals Totals
+	for _, t := range p.Taxes {
+		totals.Add(t.Totals())
+	}
+	return totals
+}
+
+// TotalTaxation 全年个税合计（含单独计税的奖金个税）
+func (p *MonthlyTaxes) TotalTaxation() Money {
+	var total Money
+	for _, t := range p.Taxes {
+		total += t.Taxation + t.BonusTaxation
+	}
+	return total
+}
+
+// TotalRestSalary 全年税后收入合计
+func (p *MonthlyTaxes) TotalRestSalary() Money {
+	var total Money
 	for _, t := range p.Taxes {
-		fmt.Println(fmt.Sprintf(printTaxInfor, t.Month, t.Salary, t.SubsidyAmount,
-			t.Insurances, t.AccumulationFund, t.Taxation, t.RestSalary))
+		total += t.RestSalary
 	}
+	return total
 }
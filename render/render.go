@@ -0,0 +1,132 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ymhhh/tax/chart"
+	"github.com/ymhhh/tax/handlers"
+	"gopkg.in/yaml.v2"
+)
+
+// Format 输出格式
+type Format string
+
+// 输出格式
+const (
+	FormatText     Format = "text"     // 各命令原有的文本输出
+	FormatJSON     Format = "json"     // 完整的计算结果
+	FormatYAML     Format = "yaml"     // 完整的计算结果
+	FormatCSV      Format = "csv"      // 表格，带合计行
+	FormatMarkdown Format = "markdown" // 表格，带合计行
+	FormatTable    Format = "table"    // 对齐的终端表格，带合计行
+	FormatXLSX     Format = "xlsx"     // Excel 工作簿，需要写入文件
+	FormatSVG      Format = "svg"      // 折线图
+)
+
+// Formats 支持的输出格式
+var Formats = []Format{FormatText, FormatJSON, FormatYAML, FormatCSV, FormatMarkdown, FormatTable, FormatXLSX, FormatSVG}
+
+// ParseFormat 解析输出格式，为空时使用文本
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatText, nil
+	}
+	for _, format := range Formats {
+		if string(format) == strings.ToLower(s) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的输出格式: %s, 可选: %s", s, formatNames())
+}
+
+func formatNames() string {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, "|")
+}
+
+// Charter 可以输出为折线图的结果
+type Charter interface {
+	Chart() *chart.Chart
+}
+
+// Printer 可以输出为文本的结果
+type Printer interface {
+	Fprint(w io.Writer)
+}
+
+// Render 按格式将结果输出到 w
+// 文本格式需要结果实现 Printer，csv、markdown、table 需要实现 handlers.Tabular，xlsx 需要实现 Workbooker 或 handlers.Tabular，svg 需要实现 Charter
+func Render(w io.Writer, format Format, v interface{}) error {
+	switch format {
+	case FormatXLSX:
+		if workbooker, ok := v.(Workbooker); ok {
+			return workbooker.Workbook().Write(w)
+		}
+	case FormatSVG:
+		charter, ok := v.(Charter)
+		if !ok {
+			return fmt.Errorf("结果不支持 %s 格式", format)
+		}
+		return charter.Chart().Write(w)
+	case FormatText:
+		printer, ok := v.(Printer)
+		if !ok {
+			return fmt.Errorf("结果不支持 %s 格式", format)
+		}
+		printer.Fprint(w)
+		return nil
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case FormatYAML:
+		bs, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bs)
+		return err
+	}
+
+	tabular, ok := v.(handlers.Tabular)
+	if !ok {
+		return fmt.Errorf("结果不支持 %s 格式", format)
+	}
+	tables := tabular.Tables()
+
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, tables)
+	case FormatMarkdown:
+		return writeMarkdown(w, tables)
+	case FormatTable:
+		return writeTable(w, tables)
+	case FormatXLSX:
+		return tablesWorkbook(tables).Write(w)
+	}
+	return fmt.Errorf("不支持的输出格式: %s", format)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package render
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ymhhh/tax/charset"
+	"github.com/ymhhh/tax/handlers"
+)
+
+// writeCSV 输出 csv，多个表格时每个表格前输出标题行，表格之间空一行
+func writeCSV(w io.Writer, tables []*handlers.Table) error {
+	writer := csv.NewWriter(w)
+	for i, t := range tables {
+		if len(tables) > 1 {
+			if i > 0 {
+				writer.Write(nil)
+			}
+			writer.Write([]string{t.Title})
+		}
+		writer.Write(t.Headers)
+		writer.WriteAll(t.Rows)
+		if len(t.Totals) > 0 {
+			writer.Write(t.Totals)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeMarkdown 输出 markdown 表格，数值列右对齐，合计行加粗
+func writeMarkdown(w io.Writer, tables []*handlers.Table) error {
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if t.Title != "" {
+			fmt.Fprintf(w, "### %s\n\n", t.Title)
+		}
+
+		numeric := numericColumns(t)
+		fmt.Fprintln(w, markdownRow(t.Headers, false))
+		aligns := make([]string, len(t.Headers))
+		for col := range aligns {
+			aligns[col] = "---"
+			if numeric[col] {
+				aligns[col] = "---:"
+			}
+		}
+		fmt.Fprintln(w, "| "+strings.Join(aligns, " | ")+" |")
+		for _, row := range t.Rows {
+			fmt.Fprintln(w, markdownRow(row, false))
+		}
+		if len(t.Totals) > 0 {
+			fmt.Fprintln(w, markdownRow(t.Totals, true))
+		}
+	}
+	return nil
+}
+
+func markdownRow(cells []string, bold bool) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		cell = strings.Replace(cell, "|", "\\|", -1)
+		if bold && cell != "" {
+			cell = "**" + cell + "**"
+		}
+		escaped[i] = cell
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
+
+// writeTable 输出按显示宽度对齐的表格，数值列右对齐
+func writeTable(w io.Writer, tables []*handlers.Table) error {
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if t.Title != "" {
+			fmt.Fprintln(w, t.Title)
+		}
+
+		rows := append([][]string{t.Headers}, t.Rows...)
+		if len(t.Totals) > 0 {
+			rows = append(rows, t.Totals)
+		}
+		widths := make([]int, len(t.Headers))
+		for _, row := range rows {
+			for col, cell := range row {
+				if col < len(widths) && charset.Width(cell) > widths[col] {
+					widths[col] = charset.Width(cell)
+				}
+			}
+		}
+
+		separator := make([]string, len(widths))
+		for col, width := range widths {
+			separator[col] = strings.Repeat("-", width)
+		}
+		numeric := numericColumns(t)
+
+		fmt.Fprintln(w, tableRow(t.Headers, widths, numeric))
+		fmt.Fprintln(w, strings.Join(separator, "  "))
+		for _, row := range t.Rows {
+			fmt.Fprintln(w, tableRow(row, widths, numeric))
+		}
+		if len(t.Totals) > 0 {
+			fmt.Fprintln(w, strings.Join(separator, "  "))
+			fmt.Fprintln(w, tableRow(t.Totals, widths, numeric))
+		}
+	}
+	return nil
+}
+
+func tableRow(cells []string, widths []int, numeric []bool) string {
+	padded := make([]string, len(widths))
+	for col, width := range widths {
+		var cell string
+		if col < len(cells) {
+			cell = cells[col]
+		}
+		pad := strings.Repeat(" ", width-charset.Width(cell))
+		if numeric[col] {
+			padded[col] = pad + cell
+		} else {
+			padded[col] = cell + pad
+		}
+	}
+	return strings.TrimRight(strings.Join(padded, "  "), " ")
+}
+
+// numericColumns 内容都是数值（或为空）的列
+func numericColumns(t *handlers.Table) []bool {
+	numeric := make([]bool, len(t.Headers))
+	for col := range numeric {
+		numeric[col] = len(t.Rows) > 0
+		for _, row := range t.Rows {
+			if col >= len(row) || row[col] == "" {
+				continue
+			}
+			if _, err := strconv.ParseFloat(row[col], 64); err != nil {
+				numeric[col] = false
+				break
+			}
+		}
+	}
+	return numeric
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package render
+
+import (
+	"strconv"
+
+	"github.com/ymhhh/tax/handlers"
+	"github.com/ymhhh/tax/xlsx"
+)
+
+// Workbooker 可以输出为自定义工作簿的结果，未实现时每个表格为一个工作表
+type Workbooker interface {
+	Workbook() *xlsx.Workbook
+}
+
+// tablesWorkbook 每个表格为一个工作表，数字列写为数字，冻结表头，合计行加粗
+func tablesWorkbook(tables []*handlers.Table) *xlsx.Workbook {
+	wb := &xlsx.Workbook{}
+	for _, t := range tables {
+		sheet := wb.AddSheet(t.Title)
+		sheet.FreezeRows = 1
+		sheet.AddRow(xlsx.Header(t.Headers...)...)
+
+		numeric := numericColumns(t)
+		for _, row := range t.Rows {
+			sheet.AddRow(cells(row, numeric)...)
+		}
+		if len(t.Totals) > 0 {
+			sheet.AddRow(xlsx.Bold(cells(t.Totals, numeric))...)
+		}
+	}
+	return wb
+}
+
+func cells(row []string, numeric []bool) []xlsx.Cell {
+	cells := make([]xlsx.Cell, len(row))
+	for i, value := range row {
+		cells[i] = xlsx.Text(value)
+		if i >= len(numeric) || !numeric[i] {
+			continue
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			cells[i] = xlsx.Number(f)
+		}
+	}
+	return cells
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ymhhh/tax/handlers"
+)
+
+// 错误码
+const (
+	CodeNotFound          = "not_found"
+	CodeMethodNotAllowed  = "method_not_allowed"
+	CodeInvalidJSON       = "invalid_json"
+	CodeInvalidQuery      = "invalid_query"
+	CodeInvalidRequest    = "invalid_request"
+	CodePolicyNotFound    = "policy_not_found"
+	CodeCalculationFailed = "calculation_failed"
+	CodeTimeout           = "timeout"
+	CodeInternal          = "internal"
+)
+
+// Error 结构化的错误，响应体为 {"error": {"code": "...", "message": "..."}}
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorBody 错误响应体
+type ErrorBody struct {
+	Error *Error `json:"error"`
+}
+
+func (p *Error) Error() string {
+	return p.Message
+}
+
+func errorf(status int, code, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func invalidRequest(err error) *Error {
+	return errorf(http.StatusBadRequest, CodeInvalidRequest, "%v", err)
+}
+
+// calculationFailed 计算失败，没有适用的政策时返回 CodePolicyNotFound
+func calculationFailed(err error) *Error {
+	if errors.Is(err, handlers.ErrPolicyNotFound) {
+		return policyNotFound(err)
+	}
+	return errorf(http.StatusUnprocessableEntity, CodeCalculationFailed, "%v", err)
+}
+
+func policyNotFound(err error) *Error {
+	return errorf(http.StatusUnprocessableEntity, CodePolicyNotFound, "%v", err)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*Error)
+	if !ok {
+		e = errorf(http.StatusInternalServerError, CodeInternal, "%v", err)
+	}
+	writeJSON(w, e.Status, &ErrorBody{Error: e})
+}
@@ -0,0 +1,316 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ymhhh/tax/handlers"
+)
+
+// 请求体的最大字节数
+const maxBodyBytes = 1 << 20
+
+// Server 个税计算的 HTTP JSON 接口，请求体和响应体沿用 handlers 中的结构
+// 每个请求可以通过 city、date、year 参数选择适用的政策
+type Server struct {
+	taxes   *handlers.TaxesHandler
+	timeout time.Duration
+	mux     *http.ServeMux
+}
+
+// New 生成 Server，timeout 为单个请求的计算超时，为 0 时不限制
+func New(taxes *handlers.TaxesHandler, timeout time.Duration) *Server {
+	s := &Server{
+		taxes:   taxes,
+		timeout: timeout,
+		mux:     http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, errorf(http.StatusNotFound, CodeNotFound, "接口不存在: %s", r.URL.Path))
+	})
+	s.mux.HandleFunc("/healthz", s.handle(http.MethodGet, s.healthz))
+	s.mux.HandleFunc("/api/v1/policies", s.handle(http.MethodGet, s.policies))
+	s.mux.HandleFunc("/api/v1/accumulation-fund", s.handle(http.MethodPost, s.accumulationFund))
+	s.mux.HandleFunc("/api/v1/insurances", s.handle(http.MethodPost, s.insurances))
+	s.mux.HandleFunc("/api/v1/taxes", s.handle(http.MethodPost, s.monthlyTaxes))
+	s.mux.HandleFunc("/api/v1/bonus", s.handle(http.MethodPost, s.bonus))
+	s.mux.HandleFunc("/api/v1/bonus/optimize", s.handle(http.MethodPost, s.optimizeBonus))
+	s.mux.HandleFunc("/api/v1/reconcile", s.handle(http.MethodPost, s.reconcile))
+	return s
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// request 读取完毕的请求，计算在单独的 goroutine 中进行，超时返回后不再访问 http.Request
+// 超时后 goroutine 会继续运行到计算结束：五险一金、月度个税、奖金和汇算只计算一个年度，
+// 耗时有上限；奖金拆分的搜索耗时随步长增长，需检查 ctx，超时后尽快停止
+type request struct {
+	ctx   context.Context
+	query url.Values
+	body  []byte
+}
+
+// decode 解析请求体，不允许出现未知的字段
+func (p *request) decode(v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(p.body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, CodeInvalidJSON, "请求体格式错误: %v", err)
+	}
+	return nil
+}
+
+type handlerFunc func(r *request) (interface{}, error)
+
+// handle 校验请求方法，读取请求体后在超时时间内计算
+func (s *Server) handle(method string, fn handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, errorf(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "只支持 %s 请求", method))
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			writeError(w, errorf(http.StatusRequestEntityTooLarge, CodeInvalidRequest, "读取请求体失败: %v", err))
+			return
+		}
+		ctx := r.Context()
+		if s.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+		}
+		req := &request{ctx: ctx, query: r.URL.Query(), body: body}
+
+		type result struct {
+			v   interface{}
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			v, err := fn(req)
+			done <- result{v: v, err: err}
+		}()
+
+		select {
+		case res := <-done:
+			if res.err != nil {
+				writeError(w, res.err)
+				return
+			}
+			writeJSON(w, http.StatusOK, res.v)
+		case <-ctx.Done():
+			writeError(w, errorf(http.StatusServiceUnavailable, CodeTimeout, "计算超时"))
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
+
+func (s *Server) healthz(r *request) (interface{}, error) {
+	return map[string]string{"status": "ok"}, nil
+}
+
+func (s *Server) policies(r *request) (interface{}, error) {
+	return s.taxes.PolicyRegistry.Summaries(), nil
+}
+
+func (s *Server) accumulationFund(r *request) (interface{}, error) {
+	info, h, err := s.personalInfo(r)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.AccumulationFundHandler.Calc(info)
+	if err != nil {
+		return nil, calculationFailed(err)
+	}
+	return result, nil
+}
+
+func (s *Server) insurances(r *request) (interface{}, error) {
+	info, h, err := s.personalInfo(r)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.InsurancesHandler.Calc(info)
+	if err != nil {
+		return nil, calculationFailed(err)
+	}
+	return result, nil
+}
+
+func (s *Server) monthlyTaxes(r *request) (interface{}, error) {
+	ss, h, err := s.salaries(r)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.Calc(ss)
+	if err != nil {
+		return nil, calculationFailed(err)
+	}
+	return result, nil
+}
+
+func (s *Server) bonus(r *request) (interface{}, error) {
+	ss, h, err := s.salaries(r)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.CalcBonus(ss)
+	if err != nil {
+		return nil, calculationFailed(err)
+	}
+	return result, nil
+}
+
+func (s *Server) optimizeBonus(r *request) (interface{}, error) {
+	step := handlers.NewMoney(1000)
+	if v := r.query.Get("step"); v != "" {
+		var err error
+		if step, err = handlers.ParseMoney(v); err != nil || step <= 0 {
+			return nil, errorf(http.StatusBadRequest, CodeInvalidQuery, "step 需为大于0的金额: %s", v)
+		}
+	}
+
+	ss, h, err := s.salaries(r)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.OptimizeBonus(r.ctx, ss, step)
+	if errors.Is(err, handlers.ErrTooManySearchPoints) {
+		return nil, errorf(http.StatusBadRequest, CodeInvalidQuery, "step 过小: %v", err)
+	}
+	if err != nil {
+		return nil, calculationFailed(err)
+	}
+	return result, nil
+}
+
+func (s *Server) reconcile(r *request) (interface{}, error) {
+	ss, h, err := s.salaries(r)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.Reconcile(ss)
+	if err != nil {
+		return nil, calculationFailed(err)
+	}
+	return result, nil
+}
+
+// personalInfo 解析个人信息，按 city、date、year 参数选出适用的政策
+func (s *Server) personalInfo(r *request) (*handlers.PersonalInfo, *handlers.TaxesHandler, error) {
+	info := &handlers.PersonalInfo{}
+	if err := r.decode(info); err != nil {
+		return nil, nil, err
+	}
+	if err := info.Validate(); err != nil {
+		return nil, nil, invalidRequest(err)
+	}
+
+	month, err := queryMonth(r.query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if city := r.query.Get("city"); city != "" {
+		info.Jurisdiction = city
+	}
+
+	h, err := s.taxes.Handler(info.Jurisdiction, month)
+	if err != nil {
+		return nil, nil, policyNotFound(err)
+	}
+	return info, h, nil
+}
+
+// salaries 解析薪资配置，city、year 参数覆盖配置中的地区和年度
+// 设置了 date 参数时全年按该月适用的政策计算，否则按每个月份各自适用的政策计算
+func (s *Server) salaries(r *request) (*handlers.Salaries, *handlers.TaxesHandler, error) {
+	ss := &handlers.Salaries{}
+	if err := r.decode(ss); err != nil {
+		return nil, nil, err
+	}
+	if err := ss.Validate(); err != nil {
+		return nil, nil, invalidRequest(err)
+	}
+
+	if city := r.query.Get("city"); city != "" {
+		ss.Jurisdiction = city
+	}
+	if v := r.query.Get("year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, nil, errorf(http.StatusBadRequest, CodeInvalidQuery, "year 格式错误: %s", v)
+		}
+		ss.Year = year
+	}
+
+	date := r.query.Get("date")
+	if date == "" {
+		return ss, s.taxes, nil
+	}
+	month, err := handlers.ParseYearMonth(date)
+	if err != nil {
+		return nil, nil, errorf(http.StatusBadRequest, CodeInvalidQuery, "%v", err)
+	}
+	h, err := s.taxes.Handler(ss.Jurisdiction, month)
+	if err != nil {
+		return nil, nil, policyNotFound(err)
+	}
+	return ss, h, nil
+}
+
+// queryMonth date 或 year 参数指定的月份，都未设置时为零值，表示最新政策
+func queryMonth(query url.Values) (handlers.YearMonth, error) {
+	if date := query.Get("date"); date != "" {
+		month, err := handlers.ParseYearMonth(date)
+		if err != nil {
+			return handlers.YearMonth{}, errorf(http.StatusBadRequest, CodeInvalidQuery, "%v", err)
+		}
+		return month, nil
+	}
+	if v := query.Get("year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			return handlers.YearMonth{}, errorf(http.StatusBadRequest, CodeInvalidQuery, "year 格式错误: %s", v)
+		}
+		return handlers.YearMonth{Year: year, Month: 1}, nil
+	}
+	return handlers.YearMonth{}, nil
+}
@@ -0,0 +1,205 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ymhhh/tax/catalog"
+	"github.com/ymhhh/tax/handlers"
+)
+
+const testSalaries = `{
+	"jurisdiction": "beijing",
+	"year": 2024,
+	"for": true,
+	"monthly_salaries": [
+		{"threshold": 5000, "salary": 30000, "subsidy_amount": 330, "accumulation_fund_rate": 12}
+	],
+	"bonus": {"amount": 60000, "month": 12}
+}`
+
+func newTestServer(t *testing.T, timeout time.Duration) *Server {
+	t.Helper()
+	taxes, err := catalog.Load()
+	if err != nil {
+		t.Fatalf("加载内置政策失败: %v", err)
+	}
+	return New(taxes, timeout)
+}
+
+func do(t *testing.T, s *Server, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) *Error {
+	t.Helper()
+	body := &ErrorBody{}
+	if err := json.Unmarshal(w.Body.Bytes(), body); err != nil || body.Error == nil {
+		t.Fatalf("错误响应体格式错误: %s", w.Body.String())
+	}
+	return body.Error
+}
+
+func TestEndpoints(t *testing.T) {
+	s := newTestServer(t, 0)
+
+	for _, c := range []struct {
+		method, target, body string
+	}{
+		{http.MethodGet, "/healthz", ""},
+		{http.MethodGet, "/api/v1/policies", ""},
+		{http.MethodPost, "/api/v1/accumulation-fund?city=beijing&year=2024", `{"salary": 30000, "accumulation_fund_rate": 12}`},
+		{http.MethodPost, "/api/v1/insurances?city=beijing&year=2024", `{"salary": 30000}`},
+		{http.MethodPost, "/api/v1/taxes", testSalaries},
+		{http.MethodPost, "/api/v1/bonus", testSalaries},
+		{http.MethodPost, "/api/v1/bonus/optimize?step=5000", testSalaries},
+		{http.MethodPost, "/api/v1/reconcile", testSalaries},
+	} {
+		w := do(t, s, c.method, c.target, c.body)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: 状态码 %d, 响应: %s", c.method, c.target, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMonthlyTaxes(t *testing.T) {
+	s := newTestServer(t, 0)
+
+	w := do(t, s, http.MethodPost, "/api/v1/taxes", testSalaries)
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 %d, 响应: %s", w.Code, w.Body.String())
+	}
+	taxes := &handlers.MonthlyTaxes{}
+	if err := json.Unmarshal(w.Body.Bytes(), taxes); err != nil {
+		t.Fatalf("响应体格式错误: %v", err)
+	}
+	if len(taxes.Taxes) != 12 {
+		t.Fatalf("应有 12 个月, 实际 %d", len(taxes.Taxes))
+	}
+	if got, want := taxes.Taxes[0].Taxation, handlers.NewMoney(557.31); got != want {
+		t.Errorf("1月个税 %s, 应为 %s", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := newTestServer(t, 0)
+
+	for _, c := range []struct {
+		method, target, body string
+		status               int
+		code                 string
+	}{
+		{http.MethodGet, "/api/v1/unknown", "", http.StatusNotFound, CodeNotFound},
+		{http.MethodGet, "/api/v1/taxes", "", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{http.MethodPost, "/api/v1/taxes", `{"salary":`, http.StatusBadRequest, CodeInvalidJSON},
+		{http.MethodPost, "/api/v1/taxes", `{"unknown": 1}`, http.StatusBadRequest, CodeInvalidJSON},
+		{http.MethodPost, "/api/v1/taxes?year=abc", testSalaries, http.StatusBadRequest, CodeInvalidQuery},
+		{http.MethodPost, "/api/v1/taxes?city=atlantis", testSalaries, http.StatusUnprocessableEntity, CodePolicyNotFound},
+		{http.MethodPost, "/api/v1/reconcile?city=atlantis", testSalaries, http.StatusUnprocessableEntity, CodePolicyNotFound},
+		{http.MethodPost, "/api/v1/taxes?city=atlantis&date=2024-03", testSalaries, http.StatusUnprocessableEntity, CodePolicyNotFound},
+		{http.MethodPost, "/api/v1/taxes?date=2018-03", testSalaries, http.StatusUnprocessableEntity, CodePolicyNotFound},
+		{http.MethodPost, "/api/v1/taxes?date=abc", testSalaries, http.StatusBadRequest, CodeInvalidQuery},
+		{http.MethodPost, "/api/v1/insurances?city=atlantis", `{"salary": 30000}`, http.StatusUnprocessableEntity, CodePolicyNotFound},
+		{http.MethodPost, "/api/v1/bonus/optimize?step=0", testSalaries, http.StatusBadRequest, CodeInvalidQuery},
+		{http.MethodPost, "/api/v1/bonus/optimize?step=-100", testSalaries, http.StatusBadRequest, CodeInvalidQuery},
+		{http.MethodPost, "/api/v1/bonus/optimize?step=0.01", testSalaries, http.StatusBadRequest, CodeInvalidQuery},
+	} {
+		w := do(t, s, c.method, c.target, c.body)
+		if w.Code != c.status {
+			t.Errorf("%s %s: 状态码 %d, 应为 %d, 响应: %s", c.method, c.target, w.Code, c.status, w.Body.String())
+			continue
+		}
+		if e := decodeError(t, w); e.Code != c.code {
+			t.Errorf("%s %s: 错误码 %s, 应为 %s", c.method, c.target, e.Code, c.code)
+		}
+	}
+}
+
+func TestMonthlyTaxesDate(t *testing.T) {
+	s := newTestServer(t, 0)
+
+	// 月薪高于基数上限，社保随各期的上限变化
+	body := strings.Replace(testSalaries, `"salary": 30000`, `"salary": 50000`, 1)
+	calc := func(target string) *handlers.MonthlyTaxes {
+		t.Helper()
+		w := do(t, s, http.MethodPost, target, body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: 状态码 %d, 响应: %s", target, w.Code, w.Body.String())
+		}
+		taxes := &handlers.MonthlyTaxes{}
+		if err := json.Unmarshal(w.Body.Bytes(), taxes); err != nil {
+			t.Fatalf("%s: 响应体格式错误: %v", target, err)
+		}
+		return taxes
+	}
+
+	// 2024 年 7 月起北京使用新的基数，未设置 date 时 1 月和 12 月的社保不同
+	monthly := calc("/api/v1/taxes")
+	if monthly.Taxes[0].Insurances == monthly.Taxes[11].Insurances {
+		t.Fatalf("1月与12月的社保均为 %s, 应按各月的政策计算", monthly.Taxes[0].Insurances)
+	}
+	// 设置 date 后全年按该月的政策计算
+	dated := calc("/api/v1/taxes?date=2024-03")
+	for _, tax := range dated.Taxes {
+		if tax.Insurances != monthly.Taxes[0].Insurances {
+			t.Errorf("%d月社保 %s, 应为 2024-03 政策下的 %s", tax.Month, tax.Insurances, monthly.Taxes[0].Insurances)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s := newTestServer(t, 20*time.Millisecond)
+
+	start := time.Now()
+	// 步长较小时搜索需要数秒，超时后应立即返回
+	w := do(t, s, http.MethodPost, "/api/v1/bonus/optimize?step=50", testSalaries)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("状态码 %d, 应为 %d, 响应: %s", w.Code, http.StatusServiceUnavailable, w.Body.String())
+	}
+	if e := decodeError(t, w); e.Code != CodeTimeout {
+		t.Errorf("错误码 %s, 应为 %s", e.Code, CodeTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("超时后 %s 才返回", elapsed)
+	}
+}
+
+func TestOptimizeBonusCanceled(t *testing.T) {
+	s := newTestServer(t, 0)
+
+	ss := &handlers.Salaries{}
+	if err := json.Unmarshal([]byte(testSalaries), ss); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// 取消后搜索应停止，不再占用计算资源
+	if _, err := s.taxes.OptimizeBonus(ctx, ss, handlers.NewMoney(50)); err != context.Canceled {
+		t.Errorf("错误 %v, 应为 %v", err, context.Canceled)
+	}
+}
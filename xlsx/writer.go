@@ -0,0 +1,318 @@
+/*
+Copyright © 2020 Henry Huang <hhh@rutcode.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ymhhh/tax/charset"
+)
+
+// Workbook 工作簿
+type Workbook struct {
+	Sheets []*Sheet
+}
+
+// Sheet 工作表
+type Sheet struct {
+	Name string
+	Rows [][]Cell
+	// 冻结的行数，一般为表头
+	FreezeRows int
+}
+
+// Cell 单元格，Numeric 时写入数字，否则写入文本
+type Cell struct {
+	Text    string
+	Number  float64
+	Numeric bool
+	Bold    bool
+}
+
+// Text 文本单元格
+func Text(s string) Cell {
+	return Cell{Text: s}
+}
+
+// Number 数字单元格，显示两位小数
+func Number(f float64) Cell {
+	return Cell{Number: f, Numeric: true}
+}
+
+// Header 表头，加粗的文本
+func Header(titles ...string) []Cell {
+	cells := make([]Cell, len(titles))
+	for i, title := range titles {
+		cells[i] = Cell{Text: title, Bold: true}
+	}
+	return cells
+}
+
+// Bold 加粗整行，用于合计行
+func Bold(cells []Cell) []Cell {
+	for i := range cells {
+		cells[i].Bold = true
+	}
+	return cells
+}
+
+// AddSheet 添加工作表，名称会去掉 Excel 不允许的字符并截断为 31 个字符，重名时加上序号
+func (p *Workbook) AddSheet(name string) *Sheet {
+	name = sheetName(name)
+	unique := name
+	for i := 2; p.hasSheet(unique); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		unique = truncate(name, maxSheetName-utf8.RuneCountInString(suffix)) + suffix
+	}
+	sheet := &Sheet{Name: unique}
+	p.Sheets = append(p.Sheets, sheet)
+	return sheet
+}
+
+func (p *Workbook) hasSheet(name string) bool {
+	for _, sheet := range p.Sheets {
+		if strings.EqualFold(sheet.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+const maxSheetName = 31
+
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "Sheet"
+	}
+	return truncate(name, maxSheetName)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// AddRow 添加一行
+func (p *Sheet) AddRow(cells ...Cell) {
+	p.Rows = append(p.Rows, cells)
+}
+
+// ColumnName 列号对应的列名，0 为 A
+func ColumnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// Write 写入 xlsx 文件
+func (p *Workbook) Write(w io.Writer) error {
+	if len(p.Sheets) == 0 {
+		p.AddSheet("Sheet")
+	}
+
+	z := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", p.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", p.workbook()},
+		{"xl/_rels/workbook.xml.rels", p.workbookRels()},
+		{"xl/styles.xml", styles},
+	}
+	for _, f := range files {
+		if err := writeFile(z, f.name, f.content); err != nil {
+			return err
+		}
+	}
+	for i, sheet := range p.Sheets {
+		if err := writeFile(z, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+func writeFile(z *zip.Writer, name, content string) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const rootRels = xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// styles 0 默认；1 数字；2 加粗文本；3 加粗数字。数字格式 4 为 #,##0.00
+const styles = xmlHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="等线"/></font><font><b/><sz val="11"/><name val="等线"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="4" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+func (p *Workbook) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range p.Sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (p *Workbook) workbook() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range p.Sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sheet.Name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func (p *Workbook) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range p.Sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(p.Sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (p *Sheet) xml() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	if p.FreezeRows > 0 {
+		fmt.Fprintf(&b, `<pane ySplit="%d" topLeftCell="A%d" activePane="bottomLeft" state="frozen"/>`, p.FreezeRows, p.FreezeRows+1)
+	}
+	b.WriteString(`</sheetView></sheetViews>`)
+
+	if widths := p.columnWidths(); len(widths) > 0 {
+		b.WriteString(`<cols>`)
+		for i, width := range widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	for r, row := range p.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := ColumnName(c) + strconv.Itoa(r+1)
+			style := 0
+			if cell.Numeric {
+				style++
+			}
+			if cell.Bold {
+				style += 2
+			}
+			switch {
+			case cell.Numeric:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(cell.Number, 'f', -1, 64))
+			case cell.Text != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(cell.Text))
+			case style != 0:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"/>`, ref, style)
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// columnWidths 按各列内容的显示宽度设置列宽，中文占两个字符
+func (p *Sheet) columnWidths() []int {
+	var widths []int
+	for _, row := range p.Rows {
+		for c, cell := range row {
+			for len(widths) <= c {
+				widths = append(widths, 8)
+			}
+			text := cell.Text
+			if cell.Numeric {
+				text = strconv.FormatFloat(cell.Number, 'f', 2, 64) + "   "
+			}
+			if width := charset.Width(text) + 2; width > widths[c] {
+				widths[c] = width
+			}
+		}
+	}
+	for i := range widths {
+		if widths[i] > 60 {
+			widths[i] = 60
+		}
+	}
+	return widths
+}
+
+// escape 转义 XML 文本，并去掉 XML 不允许的控制字符
+func escape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, s)
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}